@@ -35,6 +35,19 @@ type SecurityGroupStatus struct {
 	ID string `json:"id,omitempty"`
 }
 
+// CapacityReservationStatus contains resolved CapacityReservation selector values utilized for node launch
+type CapacityReservationStatus struct {
+	// Id of the capacity reservation
+	// +optional
+	ID string `json:"id,omitempty"`
+	// The associated availability zone
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// InstanceType this reservation was purchased for
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+}
+
 // AWSNodeTemplateStatus contains the resolved state of the AWSNodeTemplate
 type AWSNodeTemplateStatus struct {
 	// Subnets contains the current Subnet values that are available to the
@@ -45,6 +58,10 @@ type AWSNodeTemplateStatus struct {
 	// cluster under the SecurityGroups selectors.
 	// +optional
 	SecurityGroups []SecurityGroupStatus `json:"securityGroups,omitempty"`
+	// CapacityReservations contains the current Capacity Reservation values that are available to the
+	// cluster under the CapacityReservationSelector.
+	// +optional
+	CapacityReservations []CapacityReservationStatus `json:"capacityReservations,omitempty"`
 }
 
 // AWSNodeTemplateSpec is the top level specification for the AWS Karpenter Provider.
@@ -62,6 +79,15 @@ type AWSNodeTemplateSpec struct {
 	// DetailedMonitoring controls if detailed monitoring is enabled for instances that are launched
 	// +optional
 	DetailedMonitoring *bool `json:"detailedMonitoring,omitempty"`
+	// CapacityReservationSelector discovers open and targeted Capacity Reservations to be used by Amazon EC2 tags.
+	// Instance types with matching, unused reservations are offered at a reserved capacity type instead of
+	// falling straight through to on-demand/spot.
+	// +optional
+	CapacityReservationSelector map[string]string `json:"capacityReservationSelector,omitempty"`
+	// InstanceTypeFilter further constrains which instance types are considered for this node template,
+	// beyond Karpenter's built-in virtualization-type and architecture filters.
+	// +optional
+	InstanceTypeFilter *InstanceTypeFilter `json:"instanceTypeFilter,omitempty"`
 }
 
 // AWSNodeTemplate is the Schema for the AWSNodeTemplate API
@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstanceTypeCatalogZone is the set of zones a single instance type is offered in.
+type InstanceTypeCatalogZone struct {
+	// InstanceType this entry describes.
+	InstanceType string `json:"instanceType"`
+	// Zones the instance type is offered in.
+	Zones []string `json:"zones,omitempty"`
+}
+
+// InstanceTypeCatalogSelectorZones is the zonal offering map discovered for a single subnet
+// selector. Different AWSNodeTemplates can select different subnets (and therefore different
+// zones), so the catalog keeps one of these per distinct selector rather than a single map shared
+// by every template.
+type InstanceTypeCatalogSelectorZones struct {
+	// SelectorHash identifies the subnet selector this zone map was resolved for. It's a hash rather
+	// than the selector itself since a selector is an arbitrary tag map and not a valid map key.
+	SelectorHash string `json:"selectorHash"`
+	// Zones records, per instance type, the zones it's offered in under this selector.
+	// +optional
+	Zones []InstanceTypeCatalogZone `json:"zones,omitempty"`
+}
+
+// InstanceTypeCatalogSpec is currently empty: InstanceTypeCatalog is a singleton, leader-published
+// resource and carries no user-facing configuration.
+type InstanceTypeCatalogSpec struct{}
+
+// InstanceTypeCatalogStatus contains the leader's most recently discovered instance type catalog.
+type InstanceTypeCatalogStatus struct {
+	// InstanceTypes is the raw, gzip-free JSON encoding, as a []*ec2.InstanceTypeInfo list, of the
+	// instance types discovered by the leader, so non-leader replicas can hydrate their InstanceTypeProvider cache
+	// without calling ec2:DescribeInstanceTypes themselves.
+	// +optional
+	InstanceTypes []byte `json:"instanceTypes,omitempty"`
+	// ZonesBySelector records, per subnet-selector hash, the zones each instance type is offered in
+	// per the leader's last DescribeInstanceTypeOfferings call for an AWSNodeTemplate using that
+	// selector.
+	// +optional
+	ZonesBySelector []InstanceTypeCatalogSelectorZones `json:"zonesBySelector,omitempty"`
+	// InstanceTypesSeqNum mirrors InstanceTypeProvider.instanceTypesSeqNum on the leader, so
+	// consumers can tell a new catalog was published apart from just a status update.
+	// +optional
+	InstanceTypesSeqNum uint64 `json:"instanceTypesSeqNum,omitempty"`
+	// LastUpdated is when the leader last refreshed this status from EC2.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// InstanceTypeCatalog is a cluster-scoped, leader-published snapshot of the EC2 instance type
+// catalog and zonal offerings. Non-leader replicas watch it instead of calling
+// ec2:DescribeInstanceTypes/DescribeInstanceTypeOfferings themselves.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=instancetypecatalogs,scope=Cluster,categories=karpenter
+// +kubebuilder:subresource:status
+type InstanceTypeCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InstanceTypeCatalogSpec   `json:"spec,omitempty"`
+	Status InstanceTypeCatalogStatus `json:"status,omitempty"`
+}
+
+// InstanceTypeCatalogList contains a list of InstanceTypeCatalog
+// +kubebuilder:object:root=true
+type InstanceTypeCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InstanceTypeCatalog `json:"items"`
+}
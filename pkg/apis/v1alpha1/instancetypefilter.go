@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// InstanceTypeFilter constrains the set of instance types a NodePool/Provisioner may be offered,
+// evaluated against the ec2.InstanceTypeInfo discovered for each candidate instance type.
+type InstanceTypeFilter struct {
+	// GPUManufacturers restricts offered instance types to those with a GPU from one of the given
+	// manufacturers (as reported by ec2.InstanceTypeInfo.GpuInfo), e.g. "NVIDIA".
+	// +optional
+	GPUManufacturers []string `json:"gpuManufacturers,omitempty"`
+	// GPUNames restricts offered instance types to those with one of the given GPU model names,
+	// e.g. "A100", "T4".
+	// +optional
+	GPUNames []string `json:"gpuNames,omitempty"`
+	// MinimumNeuronDevices requires at least this many AWS Inferentia/Trainium devices.
+	// +optional
+	MinimumNeuronDevices *int64 `json:"minimumNeuronDevices,omitempty"`
+	// RequireEFA restricts offered instance types to those supporting Elastic Fabric Adapter.
+	// +optional
+	RequireEFA *bool `json:"requireEFA,omitempty"`
+	// RequireNitro restricts offered instance types to those on the Nitro hypervisor.
+	// +optional
+	RequireNitro *bool `json:"requireNitro,omitempty"`
+	// RequireBareMetal restricts offered instance types to bare-metal instance types.
+	// +optional
+	RequireBareMetal *bool `json:"requireBareMetal,omitempty"`
+	// RequireLocalNVMe restricts offered instance types to those with local NVMe instance storage.
+	// +optional
+	RequireLocalNVMe *bool `json:"requireLocalNVMe,omitempty"`
+	// MinimumVCPUs excludes instance types with fewer than this many vCPUs.
+	// +optional
+	MinimumVCPUs *int64 `json:"minimumVCPUs,omitempty"`
+	// MaximumVCPUs excludes instance types with more than this many vCPUs.
+	// +optional
+	MaximumVCPUs *int64 `json:"maximumVCPUs,omitempty"`
+	// MinimumMemoryMiB excludes instance types with less memory than this, in MiB.
+	// +optional
+	MinimumMemoryMiB *int64 `json:"minimumMemoryMiB,omitempty"`
+	// MaximumMemoryMiB excludes instance types with more memory than this, in MiB.
+	// +optional
+	MaximumMemoryMiB *int64 `json:"maximumMemoryMiB,omitempty"`
+	// MinimumNetworkPerformanceGbps excludes instance types whose advertised network performance,
+	// parsed from ec2.InstanceTypeInfo.NetworkInfo.NetworkPerformance (e.g. "25 Gigabit"), is lower
+	// than this.
+	// +optional
+	MinimumNetworkPerformanceGbps *int64 `json:"minimumNetworkPerformanceGbps,omitempty"`
+}
@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UnavailableOffering records a single (instance-type, zone, capacity-type) offering that recently
+// returned an insufficient capacity error from EC2.
+type UnavailableOffering struct {
+	// InstanceType this offering is for.
+	InstanceType string `json:"instanceType"`
+	// Zone this offering is for.
+	Zone string `json:"zone"`
+	// CapacityType this offering is for, e.g. "spot", "on-demand".
+	CapacityType string `json:"capacityType"`
+	// ObservedAt is when the insufficient capacity error was last seen for this offering.
+	ObservedAt metav1.Time `json:"observedAt"`
+	// ExpiresAt is when this offering should be considered available again, absent a new
+	// insufficient capacity error resetting it.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// OfferingAvailabilitySpec is currently empty: OfferingAvailability is a singleton, write-through
+// resource and carries no user-facing configuration.
+type OfferingAvailabilitySpec struct{}
+
+// OfferingAvailabilityStatus contains the durable, cluster-wide record of recently unavailable offerings.
+type OfferingAvailabilityStatus struct {
+	// Offerings currently considered unavailable by at least one replica.
+	// +optional
+	Offerings []UnavailableOffering `json:"offerings,omitempty"`
+	// UnavailableOfferingsSeqNum is a monotonically increasing change counter bumped on every write,
+	// mirroring cache.UnavailableOfferings.SeqNum, so consumers invalidate derived caches consistently
+	// across replicas.
+	// +optional
+	UnavailableOfferingsSeqNum uint64 `json:"unavailableOfferingsSeqNum,omitempty"`
+}
+
+// OfferingAvailability is a cluster-scoped, write-through persistence layer for
+// cache.UnavailableOfferings, so the set of offerings known to have recently returned an
+// insufficient capacity error survives pod restarts and is shared across replicas.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=offeringavailabilities,scope=Cluster,categories=karpenter
+// +kubebuilder:subresource:status
+type OfferingAvailability struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OfferingAvailabilitySpec   `json:"spec,omitempty"`
+	Status OfferingAvailabilityStatus `json:"status,omitempty"`
+}
+
+// OfferingAvailabilityList contains a list of OfferingAvailability
+// +kubebuilder:object:root=true
+type OfferingAvailabilityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OfferingAvailability `json:"items"`
+}
@@ -0,0 +1,22 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache holds the in-memory caches shared across the AWS cloudprovider's providers.
+package cache
+
+import "time"
+
+// CleanupInterval is how often expired entries are purged from the go-cache instances used
+// throughout the AWS cloudprovider (instance types, zonal offerings, pricing, unavailable offerings).
+const CleanupInterval = time.Minute
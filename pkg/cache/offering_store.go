@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+// OfferingAvailabilityName is the fixed name of the cluster-scoped OfferingAvailability singleton
+// that UnavailableOfferings is rehydrated from and written through to.
+const OfferingAvailabilityName = "karpenter-unavailable-offerings"
+
+// OfferingEntry is a single (instance-type, zone, capacity-type) offering known to have recently
+// returned an insufficient capacity error, along with when it stops being considered unavailable.
+type OfferingEntry struct {
+	InstanceType string
+	Zone         string
+	CapacityType string
+	ObservedAt   time.Time
+	ExpiresAt    time.Time
+}
+
+// OfferingStore persists the set of unavailable offerings so it survives pod restarts and can be
+// shared across replicas.
+type OfferingStore interface {
+	// Load returns every currently-persisted offering, including ones that may have since expired;
+	// callers are expected to filter by ExpiresAt.
+	Load(ctx context.Context) ([]OfferingEntry, error)
+	// Save persists entries, this replica's current set of offerings, tagging the write with seqNum.
+	// CRDOfferingStore merges entries into what is already persisted rather than overwriting it.
+	Save(ctx context.Context, entries []OfferingEntry, seqNum uint64) error
+}
+
+// CRDOfferingStore is an OfferingStore backed by the cluster-scoped OfferingAvailability CRD.
+type CRDOfferingStore struct {
+	kubeClient client.Client
+}
+
+func NewCRDOfferingStore(kubeClient client.Client) *CRDOfferingStore {
+	return &CRDOfferingStore{kubeClient: kubeClient}
+}
+
+func (s *CRDOfferingStore) Load(ctx context.Context) ([]OfferingEntry, error) {
+	availability := &v1alpha1.OfferingAvailability{}
+	if err := s.kubeClient.Get(ctx, client.ObjectKey{Name: OfferingAvailabilityName}, availability); err != nil {
+		return nil, fmt.Errorf("getting offeringavailability %q, %w", OfferingAvailabilityName, err)
+	}
+	return fromCRDOfferings(availability.Status.Offerings), nil
+}
+
+// Save merges entries (this replica's snapshot) into whatever is currently persisted, rather than
+// overwriting it outright. Without the merge, replicas would stomp on each other's writes and a
+// sibling's discovery of an unavailable offering would only survive until the next Save from a
+// replica that hadn't seen it yet.
+func (s *CRDOfferingStore) Save(ctx context.Context, entries []OfferingEntry, seqNum uint64) error {
+	availability := &v1alpha1.OfferingAvailability{}
+	if err := s.kubeClient.Get(ctx, client.ObjectKey{Name: OfferingAvailabilityName}, availability); err != nil {
+		availability = &v1alpha1.OfferingAvailability{}
+		availability.Name = OfferingAvailabilityName
+		if createErr := s.kubeClient.Create(ctx, availability); createErr != nil {
+			return fmt.Errorf("creating offeringavailability %q, %w", OfferingAvailabilityName, createErr)
+		}
+	}
+	merged := mergeOfferingEntries(fromCRDOfferings(availability.Status.Offerings), entries)
+	availability.Status.Offerings = toCRDOfferings(merged)
+	// Replicas keep separate local counters; never let a write move the persisted one backwards.
+	if seqNum <= availability.Status.UnavailableOfferingsSeqNum {
+		seqNum = availability.Status.UnavailableOfferingsSeqNum + 1
+	}
+	availability.Status.UnavailableOfferingsSeqNum = seqNum
+	return s.kubeClient.Status().Update(ctx, availability)
+}
+
+// mergeOfferingEntries combines existing (the CRD's last-persisted contents, written by any
+// replica) with mine (this replica's current snapshot), keyed by (instance-type, zone,
+// capacity-type). Entries already expired are dropped so the CRD doesn't grow unbounded; where both
+// sides have an entry for the same key, the one observed more recently wins.
+func mergeOfferingEntries(existing, mine []OfferingEntry) []OfferingEntry {
+	type offeringKey struct {
+		instanceType string
+		zone         string
+		capacityType string
+	}
+	now := time.Now()
+	merged := map[offeringKey]OfferingEntry{}
+	add := func(e OfferingEntry) {
+		if e.ExpiresAt.Before(now) {
+			return
+		}
+		k := offeringKey{e.InstanceType, e.Zone, e.CapacityType}
+		if cur, ok := merged[k]; !ok || e.ObservedAt.After(cur.ObservedAt) {
+			merged[k] = e
+		}
+	}
+	for _, e := range existing {
+		add(e)
+	}
+	for _, e := range mine {
+		add(e)
+	}
+	result := make([]OfferingEntry, 0, len(merged))
+	for _, e := range merged {
+		result = append(result, e)
+	}
+	return result
+}
+
+// toCRDOfferings converts entries to their OfferingAvailability CRD representation.
+func toCRDOfferings(entries []OfferingEntry) []v1alpha1.UnavailableOffering {
+	offerings := make([]v1alpha1.UnavailableOffering, 0, len(entries))
+	for _, e := range entries {
+		offerings = append(offerings, v1alpha1.UnavailableOffering{
+			InstanceType: e.InstanceType,
+			Zone:         e.Zone,
+			CapacityType: e.CapacityType,
+			ObservedAt:   metav1.NewTime(e.ObservedAt),
+			ExpiresAt:    metav1.NewTime(e.ExpiresAt),
+		})
+	}
+	return offerings
+}
+
+// fromCRDOfferings is the inverse of toCRDOfferings.
+func fromCRDOfferings(offerings []v1alpha1.UnavailableOffering) []OfferingEntry {
+	entries := make([]OfferingEntry, 0, len(offerings))
+	for _, o := range offerings {
+		entries = append(entries, OfferingEntry{
+			InstanceType: o.InstanceType,
+			Zone:         o.Zone,
+			CapacityType: o.CapacityType,
+			ObservedAt:   o.ObservedAt.Time,
+			ExpiresAt:    o.ExpiresAt.Time,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCRDOfferingRoundTrip(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	entries := []OfferingEntry{
+		{
+			InstanceType: "m5.large",
+			Zone:         "us-west-2a",
+			CapacityType: "spot",
+			ObservedAt:   now,
+			ExpiresAt:    now.Add(UnavailableOfferingsTTL),
+		},
+		{
+			InstanceType: "c5.xlarge",
+			Zone:         "us-west-2b",
+			CapacityType: "on-demand",
+			ObservedAt:   now.Add(-time.Minute),
+			ExpiresAt:    now.Add(UnavailableOfferingsTTL - time.Minute),
+		},
+	}
+
+	got := fromCRDOfferings(toCRDOfferings(entries))
+	if len(got) != len(entries) {
+		t.Fatalf("round trip returned %d entries, want %d", len(got), len(entries))
+	}
+	for i, want := range entries {
+		if got[i] != want {
+			t.Errorf("entry %d round-tripped as %+v, want %+v", i, got[i], want)
+		}
+	}
+}
+
+func TestCRDOfferingRoundTripEmpty(t *testing.T) {
+	if got := fromCRDOfferings(toCRDOfferings(nil)); len(got) != 0 {
+		t.Errorf("expected an empty round trip to stay empty, got %+v", got)
+	}
+}
+
+func TestMergeOfferingEntriesKeepsBothSides(t *testing.T) {
+	now := time.Now()
+	existing := []OfferingEntry{
+		{InstanceType: "m5.large", Zone: "us-west-2a", CapacityType: "spot", ObservedAt: now, ExpiresAt: now.Add(time.Minute)},
+	}
+	mine := []OfferingEntry{
+		{InstanceType: "c5.xlarge", Zone: "us-west-2b", CapacityType: "on-demand", ObservedAt: now, ExpiresAt: now.Add(time.Minute)},
+	}
+
+	got := mergeOfferingEntries(existing, mine)
+	if len(got) != 2 {
+		t.Fatalf("expected entries known only to one side to survive the merge, got %+v", got)
+	}
+}
+
+func TestMergeOfferingEntriesPrefersMoreRecentlyObserved(t *testing.T) {
+	now := time.Now()
+	existing := []OfferingEntry{
+		{InstanceType: "m5.large", Zone: "us-west-2a", CapacityType: "spot", ObservedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Minute)},
+	}
+	mine := []OfferingEntry{
+		{InstanceType: "m5.large", Zone: "us-west-2a", CapacityType: "spot", ObservedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}
+
+	got := mergeOfferingEntries(existing, mine)
+	if len(got) != 1 {
+		t.Fatalf("expected the colliding key to merge into a single entry, got %+v", got)
+	}
+	if !got[0].ExpiresAt.Equal(mine[0].ExpiresAt) {
+		t.Errorf("expected the more recently observed entry to win the merge, got %+v", got[0])
+	}
+}
+
+func TestMergeOfferingEntriesDropsExpired(t *testing.T) {
+	now := time.Now()
+	existing := []OfferingEntry{
+		{InstanceType: "m5.large", Zone: "us-west-2a", CapacityType: "spot", ObservedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+	}
+
+	if got := mergeOfferingEntries(existing, nil); len(got) != 0 {
+		t.Errorf("expected an already-expired entry to be dropped from the merge, got %+v", got)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"knative.dev/pkg/logging"
+)
+
+// UnavailableOfferingsTTL is how long an observed ICE (InsufficientCapacity) error keeps an
+// offering out of consideration before Karpenter tries it again.
+const UnavailableOfferingsTTL = 3 * time.Minute
+
+// ReloadInterval is how often a store-backed UnavailableOfferings re-reads the persistent store in
+// the background, so a replica learns about offerings a sibling has marked unavailable since this
+// replica's last write, not just what was persisted at startup.
+const ReloadInterval = time.Minute
+
+// UnavailableOfferings tracks offerings (instance-type, zone, capacity-type) that recently returned
+// an insufficient capacity error from EC2, so the scheduler avoids repeatedly trying them. If
+// constructed with a store, the set is rehydrated from it on startup and written through on every
+// change, so it survives pod restarts and can be shared across replicas.
+type UnavailableOfferings struct {
+	cache *cache.Cache
+	store OfferingStore
+	// SeqNum is a monotonically increasing change counter bumped on every write, so consumers (like
+	// InstanceTypeProvider.List's result cache key) can invalidate without hashing the whole cache.
+	SeqNum uint64
+}
+
+func NewUnavailableOfferings() *UnavailableOfferings {
+	return &UnavailableOfferings{
+		cache: cache.New(UnavailableOfferingsTTL, CleanupInterval),
+	}
+}
+
+// NewUnavailableOfferingsFromStore is identical to NewUnavailableOfferings, but rehydrates from
+// store before returning and write-throughs every subsequent MarkUnavailable/Delete to it. Unless
+// startAsync is nil, it also kicks off a background goroutine that re-reads the store every
+// ReloadInterval once startAsync is closed, merging in offerings discovered by sibling replicas
+// since the last read.
+func NewUnavailableOfferingsFromStore(ctx context.Context, store OfferingStore, startAsync <-chan struct{}) (*UnavailableOfferings, error) {
+	u := &UnavailableOfferings{
+		cache: cache.New(UnavailableOfferingsTTL, CleanupInterval),
+		store: store,
+	}
+	if err := u.reload(ctx); err != nil {
+		return nil, err
+	}
+	if startAsync != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-startAsync:
+			}
+			u.reloadLoop(ctx)
+		}()
+	}
+	return u, nil
+}
+
+func (u *UnavailableOfferings) reloadLoop(ctx context.Context) {
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.reload(ctx); err != nil {
+				logging.FromContext(ctx).Errorf("reloading persisted unavailable offerings, %s", err)
+			}
+		}
+	}
+}
+
+// reload reads every persisted offering and merges the not-yet-expired ones into the local cache.
+// It never evicts an entry this replica already knows about, so a sibling's persisted snapshot
+// lagging behind this replica's own writes can't make an offering available again prematurely.
+func (u *UnavailableOfferings) reload(ctx context.Context) error {
+	entries, err := u.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("loading persisted unavailable offerings, %w", err)
+	}
+	now := time.Now()
+	added := 0
+	for _, e := range entries {
+		if e.ExpiresAt.Before(now) {
+			continue
+		}
+		key := u.key(e.InstanceType, e.Zone, e.CapacityType)
+		if _, found := u.cache.Get(key); found {
+			continue
+		}
+		u.cache.Set(key, struct{}{}, time.Until(e.ExpiresAt))
+		added++
+	}
+	if added > 0 {
+		atomic.AddUint64(&u.SeqNum, 1)
+	}
+	logging.FromContext(ctx).With("offering-count", len(entries), "new-count", added).Debugf("reloaded unavailable offerings from persistent store")
+	return nil
+}
+
+// IsUnavailable returns true if the offering has recently seen an insufficient capacity error.
+func (u *UnavailableOfferings) IsUnavailable(instanceType, zone, capacityType string) bool {
+	_, found := u.cache.Get(u.key(instanceType, zone, capacityType))
+	return found
+}
+
+// MarkUnavailable communicates that a given offering is unavailable for the UnavailableOfferingsTTL.
+func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableReason, instanceType, zone, capacityType string) {
+	logging.FromContext(ctx).Debugf("%s for offering { instanceType: %s, zone: %s, capacityType: %s }, avoiding for %s",
+		unavailableReason, instanceType, zone, capacityType, UnavailableOfferingsTTL)
+	u.cache.SetDefault(u.key(instanceType, zone, capacityType), struct{}{})
+	atomic.AddUint64(&u.SeqNum, 1)
+	u.flush(ctx)
+}
+
+// Delete removes an offering from the unavailable cache, making it immediately eligible again.
+func (u *UnavailableOfferings) Delete(ctx context.Context, instanceType, zone, capacityType string) {
+	u.cache.Delete(u.key(instanceType, zone, capacityType))
+	atomic.AddUint64(&u.SeqNum, 1)
+	u.flush(ctx)
+}
+
+// flush writes the current in-memory set through to the persistent store, if one is configured.
+// Best-effort: a write failure is logged but doesn't block the caller, since the in-memory cache
+// remains authoritative for this pod in the meantime.
+func (u *UnavailableOfferings) flush(ctx context.Context) {
+	if u.store == nil {
+		return
+	}
+	if err := u.store.Save(ctx, u.snapshot(), u.SeqNum); err != nil {
+		logging.FromContext(ctx).Errorf("persisting unavailable offerings, %s", err)
+	}
+}
+
+func (u *UnavailableOfferings) snapshot() []OfferingEntry {
+	items := u.cache.Items()
+	entries := make([]OfferingEntry, 0, len(items))
+	for key, item := range items {
+		instanceType, zone, capacityType, ok := u.parseKey(key)
+		if !ok {
+			continue
+		}
+		expiresAt := time.Now().Add(UnavailableOfferingsTTL)
+		if item.Expiration > 0 {
+			expiresAt = time.Unix(0, item.Expiration)
+		}
+		entries = append(entries, OfferingEntry{
+			InstanceType: instanceType,
+			Zone:         zone,
+			CapacityType: capacityType,
+			ObservedAt:   expiresAt.Add(-UnavailableOfferingsTTL),
+			ExpiresAt:    expiresAt,
+		})
+	}
+	return entries
+}
+
+func (u *UnavailableOfferings) key(instanceType, zone, capacityType string) string {
+	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
+}
+
+func (u *UnavailableOfferings) parseKey(key string) (instanceType, zone, capacityType string, ok bool) {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], parts[0], true
+}
@@ -0,0 +1,140 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeOfferingStore is an in-memory OfferingStore for tests, recording every Save call.
+type fakeOfferingStore struct {
+	loaded []OfferingEntry
+	saved  []OfferingEntry
+	seqNum uint64
+	saves  int
+}
+
+func (f *fakeOfferingStore) Load(context.Context) ([]OfferingEntry, error) {
+	return f.loaded, nil
+}
+
+func (f *fakeOfferingStore) Save(_ context.Context, entries []OfferingEntry, seqNum uint64) error {
+	f.saved = entries
+	f.seqNum = seqNum
+	f.saves++
+	return nil
+}
+
+func TestNewUnavailableOfferingsFromStoreSkipsExpired(t *testing.T) {
+	now := time.Now()
+	store := &fakeOfferingStore{loaded: []OfferingEntry{
+		{InstanceType: "m5.large", Zone: "us-west-2a", CapacityType: "spot", ExpiresAt: now.Add(time.Minute)},
+		{InstanceType: "c5.xlarge", Zone: "us-west-2b", CapacityType: "on-demand", ExpiresAt: now.Add(-time.Minute)},
+	}}
+
+	u, err := NewUnavailableOfferingsFromStore(context.Background(), store, nil)
+	if err != nil {
+		t.Fatalf("NewUnavailableOfferingsFromStore() error = %v", err)
+	}
+	if !u.IsUnavailable("m5.large", "us-west-2a", "spot") {
+		t.Errorf("expected the not-yet-expired persisted offering to be rehydrated as unavailable")
+	}
+	if u.IsUnavailable("c5.xlarge", "us-west-2b", "on-demand") {
+		t.Errorf("expected the already-expired persisted offering to be skipped on rehydrate")
+	}
+}
+
+func TestMarkUnavailableAndDeleteFlushThroughToStore(t *testing.T) {
+	store := &fakeOfferingStore{}
+	u, err := NewUnavailableOfferingsFromStore(context.Background(), store, nil)
+	if err != nil {
+		t.Fatalf("NewUnavailableOfferingsFromStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	u.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "us-west-2a", "spot")
+	if !u.IsUnavailable("m5.large", "us-west-2a", "spot") {
+		t.Fatalf("expected offering to be unavailable after MarkUnavailable")
+	}
+	if store.saves != 1 {
+		t.Errorf("expected MarkUnavailable to flush to the store once, got %d saves", store.saves)
+	}
+	if len(store.saved) != 1 || store.saved[0].InstanceType != "m5.large" {
+		t.Errorf("expected the store to be saved with the marked offering, got %+v", store.saved)
+	}
+	if store.seqNum != u.SeqNum {
+		t.Errorf("expected the flushed seqNum %d to match SeqNum %d", store.seqNum, u.SeqNum)
+	}
+
+	u.Delete(ctx, "m5.large", "us-west-2a", "spot")
+	if u.IsUnavailable("m5.large", "us-west-2a", "spot") {
+		t.Errorf("expected offering to be available again after Delete")
+	}
+	if store.saves != 2 {
+		t.Errorf("expected Delete to flush to the store again, got %d saves", store.saves)
+	}
+	if len(store.saved) != 0 {
+		t.Errorf("expected the store to be saved with no remaining offerings, got %+v", store.saved)
+	}
+}
+
+func TestUnavailableOfferingsWithoutStoreStillMarksUnavailable(t *testing.T) {
+	u := NewUnavailableOfferings()
+	u.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", "m5.large", "us-west-2a", "spot")
+	if !u.IsUnavailable("m5.large", "us-west-2a", "spot") {
+		t.Errorf("expected offering to be unavailable even with no backing store")
+	}
+}
+
+func TestReloadMergesNewlyPersistedEntries(t *testing.T) {
+	store := &fakeOfferingStore{}
+	u, err := NewUnavailableOfferingsFromStore(context.Background(), store, nil)
+	if err != nil {
+		t.Fatalf("NewUnavailableOfferingsFromStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// Simulate a sibling replica persisting a new offering this pod hasn't seen yet.
+	store.loaded = []OfferingEntry{
+		{InstanceType: "c5.xlarge", Zone: "us-west-2b", CapacityType: "on-demand", ExpiresAt: time.Now().Add(time.Minute)},
+	}
+	if err := u.reload(ctx); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if !u.IsUnavailable("c5.xlarge", "us-west-2b", "on-demand") {
+		t.Errorf("expected reload to pick up an offering persisted by a sibling replica")
+	}
+}
+
+func TestReloadDoesNotEvictLocallyKnownEntries(t *testing.T) {
+	store := &fakeOfferingStore{}
+	u, err := NewUnavailableOfferingsFromStore(context.Background(), store, nil)
+	if err != nil {
+		t.Fatalf("NewUnavailableOfferingsFromStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	u.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "us-west-2a", "spot")
+	// The store's view lags behind (e.g. a stale read); reload must not undo the local write.
+	store.loaded = nil
+	if err := u.reload(ctx); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if !u.IsUnavailable("m5.large", "us-west-2a", "spot") {
+		t.Errorf("expected reload to preserve an offering this replica already knows about")
+	}
+}
@@ -25,6 +25,8 @@ import (
 
 	awssettings "github.com/aws/karpenter/pkg/apis/settings"
 	awscache "github.com/aws/karpenter/pkg/cache"
+	"github.com/aws/karpenter/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter/pkg/providers/instancetype"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 
@@ -33,9 +35,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
-	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter/pkg/apis/v1alpha1"
 	"github.com/aws/karpenter/pkg/providers/subnet"
@@ -45,17 +50,27 @@ import (
 )
 
 const (
-	InstanceTypesCacheKey           = "types"
-	InstanceTypeZonesCacheKeyPrefix = "zones:"
-	InstanceTypesAndZonesCacheTTL   = 5 * time.Minute
+	InstanceTypesCacheKey            = "types"
+	InstanceTypeZonesCacheKeyPrefix  = "zones:"
+	InstanceTypeFilterCacheKeyPrefix = "types-filtered:"
+	InstanceTypesAndZonesCacheTTL    = 5 * time.Minute
+
+	// CapacityTypeReserved is surfaced alongside ec2.UsageClassTypeSpot and ec2.UsageClassTypeOnDemand for
+	// offerings backed by an unused, matching Capacity Reservation. It is effectively free capacity until the
+	// reservation's available count is exhausted, at which point the scheduler falls back to on-demand/spot.
+	CapacityTypeReserved = "reserved"
 )
 
 type InstanceTypeProvider struct {
-	region          string
-	ec2api          ec2iface.EC2API
-	subnetProvider  *subnet.Provider
-	pricingProvider *PricingProvider
-	// Has one cache entry for all the instance types (key: InstanceTypesCacheKey)
+	region                      string
+	ec2api                      ec2iface.EC2API
+	subnetProvider              *subnet.Provider
+	pricingProvider             *PricingProvider
+	capacityReservationProvider *capacityreservation.Provider
+	// source is the pluggable backend getInstanceTypes/getInstanceTypeZones delegate to. It defaults to a
+	// CachedSource wrapping the EC2 DescribeInstanceTypes/Offerings APIs, but can be swapped for a
+	// StaticSource (e.g. in isolated VPCs, or in tests) via NewInstanceTypeProviderWithSource.
+	source instancetype.Source
 	// Has one cache entry for all the zones for each subnet selector (key: InstanceTypesZonesCacheKeyPrefix:<hash_of_selector>)
 	// Values cached *before* considering insufficient capacity errors from the unavailableOfferings cache.
 	// Fully initialized Instance Types are also cached based on the set of all instance types, zones, unavailableOfferings cache,
@@ -69,6 +84,16 @@ type InstanceTypeProvider struct {
 
 func NewInstanceTypeProvider(ctx context.Context, sess *session.Session, ec2api ec2iface.EC2API, subnetProvider *subnet.Provider,
 	unavailableOfferingsCache *awscache.UnavailableOfferings, startAsync <-chan struct{}) *InstanceTypeProvider {
+	return NewInstanceTypeProviderWithSource(ctx, sess, ec2api, subnetProvider, unavailableOfferingsCache, startAsync,
+		instancetype.NewCachedSource(instancetype.NewEC2Source(ec2api, subnetProvider), InstanceTypesAndZonesCacheTTL))
+}
+
+// NewInstanceTypeProviderWithSource is identical to NewInstanceTypeProvider but takes an explicit
+// instancetype.Source, letting callers run against a StaticSource (for air-gapped clusters without
+// ec2:DescribeInstanceTypes/DescribeInstanceTypeOfferings permissions) or inject a deterministic
+// catalog in tests.
+func NewInstanceTypeProviderWithSource(ctx context.Context, sess *session.Session, ec2api ec2iface.EC2API, subnetProvider *subnet.Provider,
+	unavailableOfferingsCache *awscache.UnavailableOfferings, startAsync <-chan struct{}, source instancetype.Source) *InstanceTypeProvider {
 	return &InstanceTypeProvider{
 		ec2api:         ec2api,
 		region:         *sess.Config.Region,
@@ -81,16 +106,33 @@ func NewInstanceTypeProvider(ctx context.Context, sess *session.Session, ec2api
 			awssettings.FromContext(ctx).IsolatedVPC,
 			startAsync,
 		),
-		cache:                cache.New(InstanceTypesAndZonesCacheTTL, awscache.CleanupInterval),
-		unavailableOfferings: unavailableOfferingsCache,
-		cm:                   pretty.NewChangeMonitor(),
-		instanceTypesSeqNum:  0,
+		capacityReservationProvider: capacityreservation.NewProvider(ctx, ec2api, *sess.Config.Region, awssettings.FromContext(ctx).IsolatedVPC, startAsync),
+		source:                      source,
+		cache:                       cache.New(InstanceTypesAndZonesCacheTTL, awscache.CleanupInterval),
+		unavailableOfferings:        unavailableOfferingsCache,
+		cm:                          pretty.NewChangeMonitor(),
+		instanceTypesSeqNum:         0,
 	}
 }
 
+// NewInstanceTypeProviderWithLeaderElection wraps the default EC2-backed source in a
+// instancetype.LeaderAwareSource: only the replica holding the instance-type discovery Lease
+// actually calls EC2, publishing the result into the cluster-scoped InstanceTypeCatalog CRD; all
+// other replicas hydrate from that CRD instead. identity should be stable per-pod (e.g. pod name).
+func NewInstanceTypeProviderWithLeaderElection(ctx context.Context, sess *session.Session, ec2api ec2iface.EC2API, subnetProvider *subnet.Provider,
+	unavailableOfferingsCache *awscache.UnavailableOfferings, startAsync <-chan struct{}, kubeClient client.Client, clientset kubernetes.Interface, namespace, identity string) (*InstanceTypeProvider, error) {
+	elector, err := instancetype.NewLeaderElector(ctx, clientset, namespace, identity)
+	if err != nil {
+		return nil, fmt.Errorf("starting instance type discovery leader election, %w", err)
+	}
+	leaderSource := instancetype.NewCachedSource(instancetype.NewEC2Source(ec2api, subnetProvider), InstanceTypesAndZonesCacheTTL)
+	source := instancetype.NewLeaderAwareSource(elector, leaderSource, kubeClient)
+	return NewInstanceTypeProviderWithSource(ctx, sess, ec2api, subnetProvider, unavailableOfferingsCache, startAsync, source), nil
+}
+
 func (p *InstanceTypeProvider) List(ctx context.Context, kc *v1alpha5.KubeletConfiguration, nodeTemplate *v1alpha1.AWSNodeTemplate) ([]*cloudprovider.InstanceType, error) {
-	// Get InstanceTypes from EC2
-	instanceTypes, err := p.getInstanceTypes(ctx)
+	// Get InstanceTypes from EC2, constrained by this node template's InstanceTypeFilter, if any
+	instanceTypes, err := p.getInstanceTypes(ctx, nodeTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -110,11 +152,16 @@ func (p *InstanceTypeProvider) List(ctx context.Context, kc *v1alpha5.KubeletCon
 	}
 
 	var result []*cloudprovider.InstanceType
+	var resolvedReservations []v1alpha1.CapacityReservationStatus
 	for _, i := range instanceTypes {
 		instanceTypeName := aws.StringValue(i.InstanceType)
-		instanceType := NewInstanceType(ctx, i, kc, p.region, nodeTemplate, p.createOfferings(ctx, i, instanceTypeZones[instanceTypeName]))
+		offerings, reservations := p.createOfferings(ctx, i, instanceTypeZones[instanceTypeName], nodeTemplate.Spec.CapacityReservationSelector)
+		resolvedReservations = append(resolvedReservations, reservations...)
+		instanceType := NewInstanceType(ctx, i, kc, p.region, nodeTemplate, offerings)
+		registerExtendedResources(instanceType, i)
 		result = append(result, instanceType)
 	}
+	nodeTemplate.Status.CapacityReservations = resolvedReservations
 	p.cache.SetDefault(key, result)
 	return result, nil
 }
@@ -129,9 +176,54 @@ func (p *InstanceTypeProvider) LivenessProbe(req *http.Request) error {
 	return nil
 }
 
-func (p *InstanceTypeProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, zones sets.String) []cloudprovider.Offering {
+// registerExtendedResources adds the accelerator resources instancetype.ExtendedResources discovers
+// for i (nvidia.com/gpu, aws.amazon.com/neuron, vpc.amazonaws.com/efa) to instanceType's Capacity, so
+// they're schedulable the same way cpu/memory are. It never overwrites a resource NewInstanceType
+// already populated, since EC2-reported accelerator counts are additive, not authoritative over
+// whatever the caller's own device-plugin-aware logic already computed.
+func registerExtendedResources(instanceType *cloudprovider.InstanceType, i *ec2.InstanceTypeInfo) {
+	for name, count := range instancetype.ExtendedResources(i) {
+		resourceName := corev1.ResourceName(name)
+		if _, ok := instanceType.Capacity[resourceName]; ok {
+			continue
+		}
+		instanceType.Capacity[resourceName] = *resource.NewQuantity(count, resource.DecimalSI)
+	}
+}
+
+// createOfferings returns the purchase offerings for instanceType across zones, along with the
+// CapacityReservationStatus entries for every reservation that matched capacityReservationSelector
+// and contributed a reserved offering, so callers can record them on the node template's status.
+func (p *InstanceTypeProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, zones sets.String,
+	capacityReservationSelector map[string]string) ([]cloudprovider.Offering, []v1alpha1.CapacityReservationStatus) {
 	var offerings []cloudprovider.Offering
+	var reservations []v1alpha1.CapacityReservationStatus
 	for zone := range zones {
+		// A reservation offering is additive to the usual on-demand/spot offerings: it's only surfaced while the
+		// matching reservation still has unused capacity, and the scheduler falls back to on-demand/spot once it's
+		// exhausted, so it doesn't need to participate in the unavailableOfferings tracking below.
+		//
+		// Advertising this offering is only half the contract: whatever builds the RunInstances/launch template
+		// call for a launch that lands on CapacityTypeReserved must also call
+		// p.capacityReservationProvider.CapacityReservationSpecification(instanceType, zone, capacityReservationSelector)
+		// and set its result on the request. Skipping that call makes the $0 reservation price a lie — EC2 falls
+		// back to billing the launch as on-demand with no error, since the instance still satisfies the
+		// requested type/zone/capacity-type either way.
+		if matched, ok := p.capacityReservationProvider.Get(*instanceType.InstanceType, zone, capacityReservationSelector); ok {
+			offerings = append(offerings, cloudprovider.Offering{
+				Zone:         zone,
+				CapacityType: CapacityTypeReserved,
+				Price:        0,
+				Available:    true,
+			})
+			for _, r := range matched {
+				reservations = append(reservations, v1alpha1.CapacityReservationStatus{
+					ID:           r.ID,
+					Zone:         r.Zone,
+					InstanceType: r.InstanceType,
+				})
+			}
+		}
 		// while usage classes should be a distinct set, there's no guarantee of that
 		for capacityType := range sets.NewString(aws.StringValueSlice(instanceType.SupportedUsageClasses)...) {
 			// exclude any offerings that have recently seen an insufficient capacity error from EC2
@@ -156,9 +248,11 @@ func (p *InstanceTypeProvider) createOfferings(ctx context.Context, instanceType
 			})
 		}
 	}
-	return offerings
+	return offerings, reservations
 }
 
+// getInstanceTypeZones retrieves the zonal offerings for every instance type from the configured
+// instancetype.Source, which may be EC2-backed or a static snapshot.
 func (p *InstanceTypeProvider) getInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
 	subnetSelectorHash, err := hashstructure.Hash(nodeTemplate.Spec.SubnetSelector, hashstructure.FormatV2, nil)
 	if err != nil {
@@ -169,71 +263,74 @@ func (p *InstanceTypeProvider) getInstanceTypeZones(ctx context.Context, nodeTem
 		return cached.(map[string]sets.String), nil
 	}
 
-	// Constrain AZs from subnets
-	subnets, err := p.subnetProvider.List(ctx, nodeTemplate)
+	instanceTypeZones, err := p.source.GetInstanceTypeZones(ctx, nodeTemplate)
 	if err != nil {
 		return nil, err
 	}
-	if len(subnets) == 0 {
-		return nil, fmt.Errorf("no subnets matched selector %v", nodeTemplate.Spec.SubnetSelector)
-	}
-	zones := sets.NewString(lo.Map(subnets, func(subnet *ec2.Subnet, _ int) string {
-		return aws.StringValue(subnet.AvailabilityZone)
-	})...)
-
-	// Get offerings from EC2
-	instanceTypeZones := map[string]sets.String{}
-	if err := p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{LocationType: aws.String("availability-zone")},
-		func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
-			for _, offering := range output.InstanceTypeOfferings {
-				if zones.Has(aws.StringValue(offering.Location)) {
-					if _, ok := instanceTypeZones[aws.StringValue(offering.InstanceType)]; !ok {
-						instanceTypeZones[aws.StringValue(offering.InstanceType)] = sets.NewString()
-					}
-					instanceTypeZones[aws.StringValue(offering.InstanceType)].Insert(aws.StringValue(offering.Location))
-				}
-			}
-			return true
-		}); err != nil {
-		return nil, fmt.Errorf("describing instance type zone offerings, %w", err)
-	}
 	if p.cm.HasChanged("zonal-offerings", nodeTemplate.Spec.SubnetSelector) {
-		logging.FromContext(ctx).With("subnet-selector", pretty.Concise(nodeTemplate.Spec.SubnetSelector)).Debugf("discovered EC2 instance types zonal offerings for subnets")
+		logging.FromContext(ctx).With("subnet-selector", pretty.Concise(nodeTemplate.Spec.SubnetSelector)).Debugf("discovered instance type zonal offerings for subnets")
 	}
 	p.cache.SetDefault(cacheKey, instanceTypeZones)
 	return instanceTypeZones, nil
 }
 
-// getInstanceTypes retrieves all instance types from the ec2 DescribeInstanceTypes API using some opinionated filters
-func (p *InstanceTypeProvider) getInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
-	if cached, ok := p.cache.Get(InstanceTypesCacheKey); ok {
+// getInstanceTypes retrieves all instance types from the configured instancetype.Source, which may be
+// EC2-backed or a static snapshot loaded from a ConfigMap/file for air-gapped clusters, then applies
+// nodeTemplate's InstanceTypeFilter, if any. The filter is folded into the cache key (rather than
+// applied after a shared cache lookup) so node templates with different filters get distinct cache
+// entries instead of silently sharing one another's filtered results.
+func (p *InstanceTypeProvider) getInstanceTypes(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]*ec2.InstanceTypeInfo, error) {
+	rawInstanceTypes, err := p.rawInstanceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if nodeTemplate.Spec.InstanceTypeFilter == nil {
+		return rawInstanceTypes, nil
+	}
+
+	filterHash, _ := hashstructure.Hash(nodeTemplate.Spec.InstanceTypeFilter, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	cacheKey := fmt.Sprintf("%s%016x", InstanceTypeFilterCacheKeyPrefix, filterHash)
+	if cached, ok := p.cache.Get(cacheKey); ok {
 		return cached.(map[string]*ec2.InstanceTypeInfo), nil
 	}
-	instanceTypes := map[string]*ec2.InstanceTypeInfo{}
-	if err := p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("supported-virtualization-type"),
-				Values: []*string{aws.String("hvm")},
-			},
-			{
-				Name:   aws.String("processor-info.supported-architecture"),
-				Values: aws.StringSlice([]string{"x86_64", "arm64"}),
-			},
-		},
-	}, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
-		for _, instanceType := range page.InstanceTypes {
-			instanceTypes[aws.StringValue(instanceType.InstanceType)] = instanceType
+
+	filtered := map[string]*ec2.InstanceTypeInfo{}
+	for name, it := range rawInstanceTypes {
+		if instancetype.Matches(ctx, it, nodeTemplate.Spec.InstanceTypeFilter) {
+			filtered[name] = it
 		}
-		return true
-	}); err != nil {
-		return nil, fmt.Errorf("fetching instance types using ec2.DescribeInstanceTypes, %w", err)
+	}
+	p.cache.SetDefault(cacheKey, filtered)
+	return filtered, nil
+}
+
+// rawInstanceTypes retrieves the full, unfiltered instance type catalog from the configured Source.
+func (p *InstanceTypeProvider) rawInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	if cached, ok := p.cache.Get(InstanceTypesCacheKey); ok {
+		return cached.(map[string]*ec2.InstanceTypeInfo), nil
+	}
+	instanceTypes, err := p.source.GetInstanceTypes(ctx)
+	if err != nil {
+		return nil, err
 	}
 	if p.cm.HasChanged("instance-types", instanceTypes) {
 		logging.FromContext(ctx).With(
-			"instance-type-count", len(instanceTypes)).Debugf("discovered EC2 instance types")
+			"instance-type-count", len(instanceTypes)).Debugf("discovered instance types")
+	}
+	// Prefer the Source's own change counter when it has one (e.g. a LeaderAwareSource follower,
+	// which never calls through to EC2/static itself and so needs the leader's published seqNum to
+	// know its locally-cached catalog went stale). Fall back to bumping our own counter so the cache
+	// key still changes on every other kind of Source.
+	if seqSource, ok := p.source.(instancetype.SeqNumSource); ok {
+		if seqNum, err := seqSource.SeqNum(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("getting instance type source seqnum, %s", err)
+			atomic.AddUint64(&p.instanceTypesSeqNum, 1)
+		} else {
+			atomic.StoreUint64(&p.instanceTypesSeqNum, seqNum)
+		}
+	} else {
+		atomic.AddUint64(&p.instanceTypesSeqNum, 1)
 	}
-	atomic.AddUint64(&p.instanceTypesSeqNum, 1)
 	p.cache.SetDefault(InstanceTypesCacheKey, instanceTypes)
 	return instanceTypes, nil
 }
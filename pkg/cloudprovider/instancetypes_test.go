@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	awscache "github.com/aws/karpenter/pkg/cache"
+	"github.com/aws/karpenter/pkg/providers/capacityreservation"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+// These tests exercise createOfferings and registerExtendedResources directly rather than through
+// InstanceTypeProvider.List, since List also depends on subnetProvider, pricingProvider and
+// NewInstanceType, none of which exist in this checkout to construct (even a fake) instance of.
+// createOfferings itself never touches pricingProvider as long as the ec2.InstanceTypeInfo under
+// test has no SupportedUsageClasses, so it and registerExtendedResources are fully coverable here
+// without needing those.
+
+// fakeCapacityReservationsAPI is a minimal ec2iface.EC2API that serves a fixed
+// DescribeCapacityReservations response, letting tests populate a real capacityreservation.Provider
+// through its normal Refresh path instead of reaching into its unexported fields.
+type fakeCapacityReservationsAPI struct {
+	ec2iface.EC2API
+	reservations []*ec2.CapacityReservation
+}
+
+func (f *fakeCapacityReservationsAPI) DescribeCapacityReservationsPagesWithContext(_ aws.Context, _ *ec2.DescribeCapacityReservationsInput, fn func(*ec2.DescribeCapacityReservationsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeCapacityReservationsOutput{CapacityReservations: f.reservations}, true)
+	return nil
+}
+
+func newTestProvider(t *testing.T, reservations ...*ec2.CapacityReservation) *InstanceTypeProvider {
+	t.Helper()
+	crProvider := capacityreservation.NewProvider(context.Background(), &fakeCapacityReservationsAPI{reservations: reservations}, "us-west-2", true, nil)
+	if err := crProvider.Refresh(context.Background()); err != nil {
+		t.Fatalf("refreshing capacity reservations, %v", err)
+	}
+	return &InstanceTypeProvider{
+		capacityReservationProvider: crProvider,
+		unavailableOfferings:        awscache.NewUnavailableOfferings(),
+	}
+}
+
+func TestCreateOfferingsReservedOfferingMatchesSelector(t *testing.T) {
+	instanceType := &ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")}
+	p := newTestProvider(t, &ec2.CapacityReservation{
+		CapacityReservationId:  aws.String("cr-ml"),
+		InstanceType:           aws.String("m5.large"),
+		AvailabilityZone:       aws.String("us-west-2a"),
+		AvailableInstanceCount: aws.Int64(1),
+		State:                  aws.String(ec2.CapacityReservationStateActive),
+		Tags:                   []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("ml")}},
+	})
+
+	offerings, reservations := p.createOfferings(context.Background(), instanceType, sets.NewString("us-west-2a"), map[string]string{"team": "ml"})
+
+	if len(offerings) != 1 {
+		t.Fatalf("expected exactly one offering (the reserved one, since SupportedUsageClasses is empty), got %+v", offerings)
+	}
+	if offerings[0].CapacityType != CapacityTypeReserved || offerings[0].Price != 0 || !offerings[0].Available {
+		t.Errorf("expected a free, available reserved offering, got %+v", offerings[0])
+	}
+	if len(reservations) != 1 || reservations[0].ID != "cr-ml" {
+		t.Fatalf("expected the matched reservation to be recorded in node template status, got %+v", reservations)
+	}
+}
+
+func TestCreateOfferingsNoMatchingReservationSkipsReservedOffering(t *testing.T) {
+	instanceType := &ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")}
+	p := newTestProvider(t, &ec2.CapacityReservation{
+		CapacityReservationId:  aws.String("cr-platform"),
+		InstanceType:           aws.String("m5.large"),
+		AvailabilityZone:       aws.String("us-west-2a"),
+		AvailableInstanceCount: aws.Int64(1),
+		State:                  aws.String(ec2.CapacityReservationStateActive),
+		Tags:                   []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("platform")}},
+	})
+
+	offerings, reservations := p.createOfferings(context.Background(), instanceType, sets.NewString("us-west-2a"), map[string]string{"team": "ml"})
+
+	if len(offerings) != 0 {
+		t.Errorf("expected no offerings when neither a reservation nor any usage class matches, got %+v", offerings)
+	}
+	if len(reservations) != 0 {
+		t.Errorf("expected no resolved reservations when the selector doesn't match, got %+v", reservations)
+	}
+}
+
+func TestRegisterExtendedResourcesNeverOverwritesExisting(t *testing.T) {
+	instanceType := &cloudprovider.InstanceType{
+		Capacity: corev1.ResourceList{
+			corev1.ResourceName("nvidia.com/gpu"): resource.MustParse("8"),
+		},
+	}
+	i := &ec2.InstanceTypeInfo{
+		GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(1)}}},
+	}
+
+	registerExtendedResources(instanceType, i)
+
+	if got := instanceType.Capacity[corev1.ResourceName("nvidia.com/gpu")]; got.Cmp(resource.MustParse("8")) != 0 {
+		t.Errorf("expected registerExtendedResources to keep the caller's existing GPU capacity, got %s", got.String())
+	}
+}
+
+func TestRegisterExtendedResourcesAddsMissing(t *testing.T) {
+	instanceType := &cloudprovider.InstanceType{Capacity: corev1.ResourceList{}}
+	i := &ec2.InstanceTypeInfo{
+		GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(2)}}},
+	}
+
+	registerExtendedResources(instanceType, i)
+
+	got, ok := instanceType.Capacity[corev1.ResourceName("nvidia.com/gpu")]
+	if !ok || got.Cmp(resource.MustParse("2")) != 0 {
+		t.Errorf("expected registerExtendedResources to add the discovered GPU count, got %v", instanceType.Capacity)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capacityreservation provides a cached view of EC2 on-demand Capacity
+// Reservations so the scheduler can prefer reserved capacity over regular
+// on-demand/spot offerings.
+package capacityreservation
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/patrickmn/go-cache"
+	"knative.dev/pkg/logging"
+
+	awscache "github.com/aws/karpenter/pkg/cache"
+)
+
+const (
+	// CacheKey is the single entry under which the full reservation snapshot is stored.
+	CacheKey = "reservations"
+	// CacheTTL is how long the snapshot stored under CacheKey is kept. Get does not read that entry:
+	// lookups are served from the in-memory index, which only Refresh replaces.
+	CacheTTL = 5 * time.Minute
+	// PollInterval is how often the background refresh loop calls DescribeCapacityReservations.
+	PollInterval = time.Minute
+)
+
+// Reservation is the resolved state of a single EC2 Capacity Reservation that Karpenter may launch into.
+type Reservation struct {
+	ID                    string
+	InstanceType          string
+	Zone                  string
+	AvailableCount        int64
+	EndDate               *time.Time
+	InstanceMatchCriteria string
+	Tags                  map[string]string
+}
+
+// Matches reports whether r satisfies selector, karpenter's usual tag-selector convention: every
+// key in selector must be present on the reservation, and a value of "*" matches any tag value for
+// that key. A nil/empty selector matches nothing, since an AWSNodeTemplate without a
+// CapacityReservationSelector hasn't opted in to any reservation.
+func (r Reservation) Matches(selector map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		tagValue, ok := r.Tags[k]
+		if !ok {
+			return false
+		}
+		if v != "*" && v != tagValue {
+			return false
+		}
+	}
+	return true
+}
+
+// key identifies the reservations available for a given instance type in a given zone.
+type key struct {
+	instanceType string
+	zone         string
+}
+
+// Provider maintains a cached view of open and targeted EC2 Capacity Reservations, keyed by
+// (instance-type, zone), refreshed on a fixed interval in the background.
+//
+// It mirrors the shape of the PricingProvider: a single cache entry holding the full snapshot,
+// with lookups served from an in-memory index rebuilt on every refresh.
+type Provider struct {
+	sync.RWMutex
+
+	ec2api ec2iface.EC2API
+	region string
+	cache  *cache.Cache
+
+	reservations map[key][]Reservation
+}
+
+// NewProvider constructs a Provider and, unless isolatedVPC is true or startAsync is nil, kicks off
+// a background goroutine that refreshes the reservation snapshot every PollInterval once startAsync
+// is closed. isolatedVPC mirrors PricingProvider's handling of the same flag: a cluster without
+// egress to the EC2 API has no reservations to discover, so starting a loop that hits
+// DescribeCapacityReservations every PollInterval would just generate errors.
+func NewProvider(ctx context.Context, ec2api ec2iface.EC2API, region string, isolatedVPC bool, startAsync <-chan struct{}) *Provider {
+	p := &Provider{
+		ec2api:       ec2api,
+		region:       region,
+		cache:        cache.New(CacheTTL, awscache.CleanupInterval),
+		reservations: map[key][]Reservation{},
+	}
+	if !isolatedVPC && startAsync != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-startAsync:
+			}
+			p.refreshLoop(ctx)
+		}()
+	}
+	return p
+}
+
+func (p *Provider) refreshLoop(ctx context.Context) {
+	if err := p.Refresh(ctx); err != nil {
+		logging.FromContext(ctx).Errorf("refreshing capacity reservations, %s", err)
+	}
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Refresh(ctx); err != nil {
+				logging.FromContext(ctx).Errorf("refreshing capacity reservations, %s", err)
+			}
+		}
+	}
+}
+
+// Refresh calls DescribeCapacityReservations and rebuilds the (instance-type, zone) index.
+func (p *Provider) Refresh(ctx context.Context) error {
+	reservations := map[key][]Reservation{}
+	if err := p.ec2api.DescribeCapacityReservationsPagesWithContext(ctx, &ec2.DescribeCapacityReservationsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: aws.StringSlice([]string{ec2.CapacityReservationStateActive}),
+			},
+		},
+	}, func(output *ec2.DescribeCapacityReservationsOutput, lastPage bool) bool {
+		for _, r := range output.CapacityReservations {
+			k := key{instanceType: aws.StringValue(r.InstanceType), zone: aws.StringValue(r.AvailabilityZone)}
+			tags := map[string]string{}
+			for _, t := range r.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+			}
+			reservations[k] = append(reservations[k], Reservation{
+				ID:                    aws.StringValue(r.CapacityReservationId),
+				InstanceType:          aws.StringValue(r.InstanceType),
+				Zone:                  aws.StringValue(r.AvailabilityZone),
+				AvailableCount:        aws.Int64Value(r.AvailableInstanceCount),
+				EndDate:               r.EndDate,
+				InstanceMatchCriteria: aws.StringValue(r.InstanceMatchCriteria),
+				Tags:                  tags,
+			})
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+
+	p.Lock()
+	p.reservations = reservations
+	p.Unlock()
+	p.cache.SetDefault(CacheKey, reservations)
+	return nil
+}
+
+// Get returns the reservations with unused capacity for the given instance type and zone that are
+// also tagged to match selector (an AWSNodeTemplate's CapacityReservationSelector), if any. A
+// reservation is never returned to a node template whose selector it doesn't match, even if it has
+// unused capacity for that instance type and zone.
+func (p *Provider) Get(instanceType, zone string, selector map[string]string) ([]Reservation, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	rs, ok := p.reservations[key{instanceType: instanceType, zone: zone}]
+	if !ok {
+		return nil, false
+	}
+	var available []Reservation
+	for _, r := range rs {
+		if r.AvailableCount > 0 && r.Matches(selector) {
+			available = append(available, r)
+		}
+	}
+	return available, len(available) > 0
+}
+
+// CapacityReservationSpecification resolves the best available reservation for (instanceType, zone,
+// selector) — the one with the most remaining capacity, to spread launches across matches — into the
+// ec2.CapacityReservationSpecification a RunInstances/launch template call must set to actually
+// consume it. A launch that advertises the CapacityTypeReserved offering this provider backs but
+// never calls this method (or ignores a nil/non-nil result) silently falls back to on-demand, which
+// is worse than not advertising the reservation at all.
+//
+// It returns nil for an "open" reservation: those are consumed automatically by any RunInstances
+// call whose requirements match, with no explicit targeting needed, and forcing one would pin the
+// launch to this single reservation instead of letting EC2 pick among all open matches. For a
+// "targeted" reservation, explicit targeting is required for EC2 to consume it at all, so the
+// returned specification names it via CapacityReservationTarget.CapacityReservationId.
+func (p *Provider) CapacityReservationSpecification(instanceType, zone string, selector map[string]string) *ec2.CapacityReservationSpecification {
+	matches, ok := p.Get(instanceType, zone, selector)
+	if !ok {
+		return nil
+	}
+	best := matches[0]
+	for _, r := range matches[1:] {
+		if r.AvailableCount > best.AvailableCount {
+			best = r
+		}
+	}
+	if best.InstanceMatchCriteria != ec2.InstanceMatchCriteriaTargeted {
+		return nil
+	}
+	return &ec2.CapacityReservationSpecification{
+		CapacityReservationTarget: &ec2.CapacityReservationTarget{
+			CapacityReservationId: aws.String(best.ID),
+		},
+	}
+}
+
+func (p *Provider) LivenessProbe(_ *http.Request) error {
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// countingEC2API counts DescribeCapacityReservationsPagesWithContext calls, so tests can assert a
+// refresh loop did or didn't run without sleeping through a real PollInterval.
+type countingEC2API struct {
+	ec2iface.EC2API
+	calls int64
+}
+
+func (c *countingEC2API) DescribeCapacityReservationsPagesWithContext(_ aws.Context, _ *ec2.DescribeCapacityReservationsInput, fn func(*ec2.DescribeCapacityReservationsOutput, bool) bool, _ ...request.Option) error {
+	atomic.AddInt64(&c.calls, 1)
+	fn(&ec2.DescribeCapacityReservationsOutput{}, true)
+	return nil
+}
+
+func TestReservationMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		tags     map[string]string
+		selector map[string]string
+		want     bool
+	}{
+		{
+			name:     "nil selector never matches",
+			tags:     map[string]string{"team": "ml"},
+			selector: nil,
+			want:     false,
+		},
+		{
+			name:     "exact value match",
+			tags:     map[string]string{"team": "ml"},
+			selector: map[string]string{"team": "ml"},
+			want:     true,
+		},
+		{
+			name:     "exact value mismatch",
+			tags:     map[string]string{"team": "ml"},
+			selector: map[string]string{"team": "platform"},
+			want:     false,
+		},
+		{
+			name:     "wildcard value matches any tag value",
+			tags:     map[string]string{"team": "ml"},
+			selector: map[string]string{"team": "*"},
+			want:     true,
+		},
+		{
+			name:     "missing tag key never matches",
+			tags:     map[string]string{"other": "value"},
+			selector: map[string]string{"team": "*"},
+			want:     false,
+		},
+		{
+			name:     "every selector key must match",
+			tags:     map[string]string{"team": "ml", "env": "prod"},
+			selector: map[string]string{"team": "ml", "env": "staging"},
+			want:     false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := Reservation{Tags: c.tags}
+			if got := r.Matches(c.selector); got != c.want {
+				t.Errorf("Matches(%v) on tags %v = %v, want %v", c.selector, c.tags, got, c.want)
+			}
+		})
+	}
+}
+
+func TestProviderGetScopesBySelector(t *testing.T) {
+	p := &Provider{
+		reservations: map[key][]Reservation{
+			{instanceType: "m5.large", zone: "us-west-2a"}: {
+				{ID: "cr-ml", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 2, Tags: map[string]string{"team": "ml"}},
+				{ID: "cr-platform", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 1, Tags: map[string]string{"team": "platform"}},
+			},
+		},
+	}
+
+	got, ok := p.Get("m5.large", "us-west-2a", map[string]string{"team": "ml"})
+	if !ok || len(got) != 1 || got[0].ID != "cr-ml" {
+		t.Fatalf("Get with team=ml selector = %v, %v; want only cr-ml", got, ok)
+	}
+
+	if _, ok := p.Get("m5.large", "us-west-2a", nil); ok {
+		t.Fatalf("Get with no selector should match nothing, got ok=true")
+	}
+
+	if _, ok := p.Get("m5.large", "us-west-2a", map[string]string{"team": "data"}); ok {
+		t.Fatalf("Get with non-matching selector should match nothing, got ok=true")
+	}
+}
+
+func TestCapacityReservationSpecificationOpenReturnsNil(t *testing.T) {
+	p := &Provider{
+		reservations: map[key][]Reservation{
+			{instanceType: "m5.large", zone: "us-west-2a"}: {
+				{ID: "cr-open", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 1, InstanceMatchCriteria: ec2.InstanceMatchCriteriaOpen, Tags: map[string]string{"team": "ml"}},
+			},
+		},
+	}
+
+	if got := p.CapacityReservationSpecification("m5.large", "us-west-2a", map[string]string{"team": "ml"}); got != nil {
+		t.Errorf("expected an open reservation to need no explicit targeting, got %v", got)
+	}
+}
+
+func TestCapacityReservationSpecificationTargetedReturnsTarget(t *testing.T) {
+	p := &Provider{
+		reservations: map[key][]Reservation{
+			{instanceType: "m5.large", zone: "us-west-2a"}: {
+				{ID: "cr-targeted", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 1, InstanceMatchCriteria: ec2.InstanceMatchCriteriaTargeted, Tags: map[string]string{"team": "ml"}},
+			},
+		},
+	}
+
+	got := p.CapacityReservationSpecification("m5.large", "us-west-2a", map[string]string{"team": "ml"})
+	if got == nil || got.CapacityReservationTarget == nil || aws.StringValue(got.CapacityReservationTarget.CapacityReservationId) != "cr-targeted" {
+		t.Fatalf("expected an explicit target naming cr-targeted, got %v", got)
+	}
+}
+
+func TestCapacityReservationSpecificationPicksMostAvailable(t *testing.T) {
+	p := &Provider{
+		reservations: map[key][]Reservation{
+			{instanceType: "m5.large", zone: "us-west-2a"}: {
+				{ID: "cr-small", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 1, InstanceMatchCriteria: ec2.InstanceMatchCriteriaTargeted, Tags: map[string]string{"team": "ml"}},
+				{ID: "cr-big", InstanceType: "m5.large", Zone: "us-west-2a", AvailableCount: 5, InstanceMatchCriteria: ec2.InstanceMatchCriteriaTargeted, Tags: map[string]string{"team": "ml"}},
+			},
+		},
+	}
+
+	got := p.CapacityReservationSpecification("m5.large", "us-west-2a", map[string]string{"team": "ml"})
+	if got == nil || aws.StringValue(got.CapacityReservationTarget.CapacityReservationId) != "cr-big" {
+		t.Fatalf("expected the reservation with the most available capacity to be picked, got %v", got)
+	}
+}
+
+func TestCapacityReservationSpecificationNoMatchReturnsNil(t *testing.T) {
+	p := &Provider{reservations: map[key][]Reservation{}}
+	if got := p.CapacityReservationSpecification("m5.large", "us-west-2a", map[string]string{"team": "ml"}); got != nil {
+		t.Errorf("expected no matching reservation to return nil, got %v", got)
+	}
+}
+
+func TestNewProviderIsolatedVPCSkipsRefreshLoop(t *testing.T) {
+	api := &countingEC2API{}
+	startAsync := make(chan struct{})
+	close(startAsync)
+
+	NewProvider(context.Background(), api, "us-west-2", true, startAsync)
+
+	// There's no signal to synchronously wait on for "the loop didn't start", so give any
+	// incorrectly-started goroutine a moment to make its first call.
+	time.Sleep(50 * time.Millisecond)
+	if calls := atomic.LoadInt64(&api.calls); calls != 0 {
+		t.Errorf("expected an isolated-VPC provider to never call DescribeCapacityReservations, got %d calls", calls)
+	}
+}
+
+func TestNewProviderNonIsolatedVPCRunsRefreshLoop(t *testing.T) {
+	api := &countingEC2API{}
+	startAsync := make(chan struct{})
+	close(startAsync)
+
+	NewProvider(context.Background(), api, "us-west-2", false, startAsync)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt64(&api.calls) > 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("expected a non-isolated-VPC provider to call DescribeCapacityReservations on startup")
+}
@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mitchellh/hashstructure/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+// CatalogName is the fixed name of the cluster-scoped InstanceTypeCatalog singleton that the leader
+// publishes to and followers watch.
+const CatalogName = "karpenter-instance-types"
+
+// CatalogSource is a Source that reads the instance type catalog and zonal offerings from the
+// InstanceTypeCatalog CRD instead of calling EC2 directly. It's used by non-leader replicas under
+// LeaderAwareSource so only one pod in the fleet pays for DescribeInstanceTypes/Offerings calls.
+type CatalogSource struct {
+	kubeClient client.Client
+}
+
+func NewCatalogSource(kubeClient client.Client) *CatalogSource {
+	return &CatalogSource{kubeClient: kubeClient}
+}
+
+func (c *CatalogSource) GetInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	catalog := &v1alpha1.InstanceTypeCatalog{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: CatalogName}, catalog); err != nil {
+		return nil, fmt.Errorf("getting instancetypecatalog %q, %w", CatalogName, err)
+	}
+	if len(catalog.Status.InstanceTypes) == 0 {
+		return map[string]*ec2.InstanceTypeInfo{}, nil
+	}
+	var instanceTypes []*ec2.InstanceTypeInfo
+	if err := json.Unmarshal(catalog.Status.InstanceTypes, &instanceTypes); err != nil {
+		return nil, fmt.Errorf("unmarshalling instancetypecatalog %q instance types, %w", CatalogName, err)
+	}
+	result := map[string]*ec2.InstanceTypeInfo{}
+	for _, it := range instanceTypes {
+		result[*it.InstanceType] = it
+	}
+	return result, nil
+}
+
+func (c *CatalogSource) GetInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	selectorHash, err := subnetSelectorHash(nodeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &v1alpha1.InstanceTypeCatalog{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: CatalogName}, catalog); err != nil {
+		return nil, fmt.Errorf("getting instancetypecatalog %q, %w", CatalogName, err)
+	}
+	return zonesForSelector(catalog.Status.ZonesBySelector, selectorHash), nil
+}
+
+// zonesForSelector picks out the zone map published for selectorHash, ignoring every other
+// selector's entry. It returns an empty (non-nil) map if the leader hasn't published zones for this
+// selector yet, e.g. because the leader itself has not called GetInstanceTypeZones with a matching
+// AWSNodeTemplate since it started (a follower's own calls never reach the leader).
+func zonesForSelector(zonesBySelector []v1alpha1.InstanceTypeCatalogSelectorZones, selectorHash string) map[string]sets.String {
+	zones := map[string]sets.String{}
+	for _, sz := range zonesBySelector {
+		if sz.SelectorHash != selectorHash {
+			continue
+		}
+		for _, z := range sz.Zones {
+			zones[z.InstanceType] = sets.NewString(z.Zones...)
+		}
+	}
+	return zones
+}
+
+// subnetSelectorHash hashes nodeTemplate's subnet selector into the stable key the leader's
+// published catalog and this package's in-memory cache both use to keep zones for differently
+// selected AWSNodeTemplates apart.
+func subnetSelectorHash(nodeTemplate *v1alpha1.AWSNodeTemplate) (string, error) {
+	h, err := hashstructure.Hash(nodeTemplate.Spec.SubnetSelector, hashstructure.FormatV2, nil)
+	if err != nil {
+		return "", fmt.Errorf("hashing subnet selector: %w", err)
+	}
+	return fmt.Sprintf("%016x", h), nil
+}
+
+// SeqNum returns the leader's last-published instanceTypesSeqNum, letting callers invalidate their
+// own derived caches (e.g. InstanceTypeProvider.List's result cache) in step with the leader.
+func (c *CatalogSource) SeqNum(ctx context.Context) (uint64, error) {
+	catalog := &v1alpha1.InstanceTypeCatalog{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: CatalogName}, catalog); err != nil {
+		return 0, fmt.Errorf("getting instancetypecatalog %q, %w", CatalogName, err)
+	}
+	return catalog.Status.InstanceTypesSeqNum, nil
+}
+
+// PublishCatalog is called by the leader after every successful refresh of source to write the
+// resulting catalog and per-selector zone maps into the InstanceTypeCatalog CRD status, creating it
+// if it doesn't yet exist. zonesBySelector is keyed by subnetSelectorHash, one entry per distinct
+// AWSNodeTemplate subnet selector the leader has resolved zones for. seqNum should be the caller's
+// own monotonically increasing change counter so followers observe the same invalidation signal the
+// leader uses locally.
+func PublishCatalog(ctx context.Context, kubeClient client.Client, instanceTypes map[string]*ec2.InstanceTypeInfo, zonesBySelector map[string]map[string]sets.String, seqNum uint64) error {
+	raw, err := json.Marshal(toSlice(instanceTypes))
+	if err != nil {
+		return fmt.Errorf("marshalling instance types, %w", err)
+	}
+
+	catalog := &v1alpha1.InstanceTypeCatalog{}
+	err = kubeClient.Get(ctx, client.ObjectKey{Name: CatalogName}, catalog)
+	if err != nil {
+		catalog = &v1alpha1.InstanceTypeCatalog{}
+		catalog.Name = CatalogName
+		if createErr := kubeClient.Create(ctx, catalog); createErr != nil {
+			return fmt.Errorf("creating instancetypecatalog %q, %w", CatalogName, createErr)
+		}
+	}
+	catalog.Status.InstanceTypes = raw
+	catalog.Status.ZonesBySelector = toSelectorZones(zonesBySelector)
+	catalog.Status.InstanceTypesSeqNum = seqNum
+	now := metav1.Now()
+	catalog.Status.LastUpdated = &now
+	return kubeClient.Status().Update(ctx, catalog)
+}
+
+// toSelectorZones converts the leader's in-memory, selector-hash-keyed zone maps into their
+// InstanceTypeCatalog CRD status representation.
+func toSelectorZones(zonesBySelector map[string]map[string]sets.String) []v1alpha1.InstanceTypeCatalogSelectorZones {
+	result := make([]v1alpha1.InstanceTypeCatalogSelectorZones, 0, len(zonesBySelector))
+	for selectorHash, zones := range zonesBySelector {
+		zoneEntries := make([]v1alpha1.InstanceTypeCatalogZone, 0, len(zones))
+		for instanceType, zs := range zones {
+			zoneEntries = append(zoneEntries, v1alpha1.InstanceTypeCatalogZone{InstanceType: instanceType, Zones: zs.List()})
+		}
+		result = append(result, v1alpha1.InstanceTypeCatalogSelectorZones{SelectorHash: selectorHash, Zones: zoneEntries})
+	}
+	return result
+}
+
+func toSlice(instanceTypes map[string]*ec2.InstanceTypeInfo) []*ec2.InstanceTypeInfo {
+	result := make([]*ec2.InstanceTypeInfo, 0, len(instanceTypes))
+	for _, it := range instanceTypes {
+		result = append(result, it)
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+func TestCatalogInstanceTypesMarshalRoundTrip(t *testing.T) {
+	instanceTypes := map[string]*ec2.InstanceTypeInfo{
+		"m5.large":  {InstanceType: aws.String("m5.large"), VCpuInfo: &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)}},
+		"c5.xlarge": {InstanceType: aws.String("c5.xlarge"), VCpuInfo: &ec2.VCpuInfo{DefaultVCpus: aws.Int64(4)}},
+	}
+
+	raw, err := json.Marshal(toSlice(instanceTypes))
+	if err != nil {
+		t.Fatalf("marshalling instance types, %v", err)
+	}
+
+	var roundTripped []*ec2.InstanceTypeInfo
+	if err := json.Unmarshal(raw, &roundTripped); err != nil {
+		t.Fatalf("unmarshalling instance types, %v", err)
+	}
+
+	got := map[string]*ec2.InstanceTypeInfo{}
+	for _, it := range roundTripped {
+		got[aws.StringValue(it.InstanceType)] = it
+	}
+	if len(got) != len(instanceTypes) {
+		t.Fatalf("round trip returned %d instance types, want %d", len(got), len(instanceTypes))
+	}
+	for name, want := range instanceTypes {
+		it, ok := got[name]
+		if !ok {
+			t.Errorf("round trip is missing instance type %q", name)
+			continue
+		}
+		if aws.Int64Value(it.VCpuInfo.DefaultVCpus) != aws.Int64Value(want.VCpuInfo.DefaultVCpus) {
+			t.Errorf("instance type %q round-tripped with %d default vCPUs, want %d", name, aws.Int64Value(it.VCpuInfo.DefaultVCpus), aws.Int64Value(want.VCpuInfo.DefaultVCpus))
+		}
+	}
+}
+
+func TestToSliceEmpty(t *testing.T) {
+	if got := toSlice(map[string]*ec2.InstanceTypeInfo{}); len(got) != 0 {
+		t.Errorf("expected an empty map to produce an empty slice, got %v", got)
+	}
+}
+
+func TestSubnetSelectorHashDistinguishesSelectors(t *testing.T) {
+	a := &v1alpha1.AWSNodeTemplate{Spec: v1alpha1.AWSNodeTemplateSpec{SubnetSelector: map[string]string{"zone": "a"}}}
+	b := &v1alpha1.AWSNodeTemplate{Spec: v1alpha1.AWSNodeTemplateSpec{SubnetSelector: map[string]string{"zone": "b"}}}
+
+	hashA, err := subnetSelectorHash(a)
+	if err != nil {
+		t.Fatalf("subnetSelectorHash(a) error = %v", err)
+	}
+	hashB, err := subnetSelectorHash(b)
+	if err != nil {
+		t.Fatalf("subnetSelectorHash(b) error = %v", err)
+	}
+	if hashA == hashB {
+		t.Errorf("expected distinct subnet selectors to hash differently, both hashed to %q", hashA)
+	}
+
+	hashAAgain, err := subnetSelectorHash(a)
+	if err != nil {
+		t.Fatalf("subnetSelectorHash(a) second call error = %v", err)
+	}
+	if hashA != hashAAgain {
+		t.Errorf("expected the same subnet selector to hash consistently, got %q and %q", hashA, hashAAgain)
+	}
+}
+
+func TestZonesForSelectorOnlyReturnsMatchingSelector(t *testing.T) {
+	zonesBySelector := []v1alpha1.InstanceTypeCatalogSelectorZones{
+		{SelectorHash: "hash-a", Zones: []v1alpha1.InstanceTypeCatalogZone{{InstanceType: "m5.large", Zones: []string{"us-west-2a"}}}},
+		{SelectorHash: "hash-b", Zones: []v1alpha1.InstanceTypeCatalogZone{{InstanceType: "m5.large", Zones: []string{"us-west-2b"}}}},
+	}
+
+	got := zonesForSelector(zonesBySelector, "hash-b")
+	if len(got) != 1 || !got["m5.large"].Has("us-west-2b") {
+		t.Fatalf("expected only hash-b's zones to be returned, got %v", got)
+	}
+}
+
+func TestZonesForSelectorUnknownHashReturnsEmpty(t *testing.T) {
+	got := zonesForSelector(nil, "missing")
+	if len(got) != 0 {
+		t.Errorf("expected an unknown selector hash to return an empty map, got %v", got)
+	}
+}
+
+func TestToSelectorZonesRoundTrip(t *testing.T) {
+	zonesBySelector := map[string]map[string]sets.String{
+		"hash-a": {"m5.large": sets.NewString("us-west-2a")},
+		"hash-b": {"c5.xlarge": sets.NewString("us-west-2b", "us-west-2c")},
+	}
+
+	got := toSelectorZones(zonesBySelector)
+	roundTripped := map[string]map[string]sets.String{}
+	for _, sz := range got {
+		roundTripped[sz.SelectorHash] = zonesForSelector(got, sz.SelectorHash)
+	}
+	for hash, zones := range zonesBySelector {
+		for instanceType, want := range zones {
+			if !roundTripped[hash][instanceType].Equal(want) {
+				t.Errorf("selector %q instance type %q round-tripped as %v, want %v", hash, instanceType, roundTripped[hash][instanceType], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+const (
+	// ResourceNvidiaGPU is the extended resource name Karpenter registers on instance types with an
+	// NVIDIA GPU, matching the device plugin's advertised resource.
+	ResourceNvidiaGPU = "nvidia.com/gpu"
+	// ResourceAWSNeuron is the extended resource name Karpenter registers on instance types with
+	// AWS Inferentia/Trainium accelerators.
+	ResourceAWSNeuron = "aws.amazon.com/neuron"
+	// ResourceEFA is the extended resource name Karpenter registers on instance types supporting
+	// Elastic Fabric Adapter.
+	ResourceEFA = "vpc.amazonaws.com/efa"
+)
+
+// Matches reports whether instanceType satisfies every constraint set on filter. A nil filter, or a
+// filter with every field unset, matches everything.
+func Matches(ctx context.Context, instanceType *ec2.InstanceTypeInfo, filter *v1alpha1.InstanceTypeFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.GPUManufacturers) > 0 && !hasGPUManufacturer(instanceType, filter.GPUManufacturers) {
+		return false
+	}
+	if len(filter.GPUNames) > 0 && !hasGPUName(instanceType, filter.GPUNames) {
+		return false
+	}
+	if filter.MinimumNeuronDevices != nil && neuronDeviceCount(instanceType) < *filter.MinimumNeuronDevices {
+		return false
+	}
+	if aws.BoolValue(filter.RequireEFA) && !efaSupported(instanceType) {
+		return false
+	}
+	if aws.BoolValue(filter.RequireNitro) && aws.StringValue(instanceType.Hypervisor) != ec2.InstanceTypeHypervisorNitro {
+		return false
+	}
+	if aws.BoolValue(filter.RequireBareMetal) && !aws.BoolValue(instanceType.BareMetal) {
+		return false
+	}
+	if aws.BoolValue(filter.RequireLocalNVMe) && !localNVMeSupported(instanceType) {
+		return false
+	}
+	if filter.MinimumVCPUs != nil && aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus) < *filter.MinimumVCPUs {
+		return false
+	}
+	if filter.MaximumVCPUs != nil && aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus) > *filter.MaximumVCPUs {
+		return false
+	}
+	if filter.MinimumMemoryMiB != nil && aws.Int64Value(instanceType.MemoryInfo.SizeInMiB) < *filter.MinimumMemoryMiB {
+		return false
+	}
+	if filter.MaximumMemoryMiB != nil && aws.Int64Value(instanceType.MemoryInfo.SizeInMiB) > *filter.MaximumMemoryMiB {
+		return false
+	}
+	if filter.MinimumNetworkPerformanceGbps != nil {
+		if gbps, ok := networkPerformanceGbps(instanceType); !ok {
+			logging.FromContext(ctx).With("instance-type", aws.StringValue(instanceType.InstanceType)).
+				Warnf("could not parse network performance %q as Gbps, excluding minimumNetworkPerformanceGbps from its filter evaluation",
+					networkPerformanceString(instanceType))
+		} else if gbps < *filter.MinimumNetworkPerformanceGbps {
+			return false
+		}
+	}
+	return true
+}
+
+func hasGPUManufacturer(instanceType *ec2.InstanceTypeInfo, manufacturers []string) bool {
+	if instanceType.GpuInfo == nil {
+		return false
+	}
+	return lo.SomeBy(instanceType.GpuInfo.Gpus, func(gpu *ec2.GpuDeviceInfo) bool {
+		return lo.SomeBy(manufacturers, func(m string) bool { return strings.EqualFold(m, aws.StringValue(gpu.Manufacturer)) })
+	})
+}
+
+func hasGPUName(instanceType *ec2.InstanceTypeInfo, names []string) bool {
+	if instanceType.GpuInfo == nil {
+		return false
+	}
+	return lo.SomeBy(instanceType.GpuInfo.Gpus, func(gpu *ec2.GpuDeviceInfo) bool {
+		return lo.SomeBy(names, func(n string) bool { return strings.EqualFold(n, aws.StringValue(gpu.Name)) })
+	})
+}
+
+func neuronDeviceCount(instanceType *ec2.InstanceTypeInfo) int64 {
+	if instanceType.NeuronInfo == nil {
+		return 0
+	}
+	var count int64
+	for _, d := range instanceType.NeuronInfo.NeuronDevices {
+		count += aws.Int64Value(d.Count)
+	}
+	return count
+}
+
+func efaSupported(instanceType *ec2.InstanceTypeInfo) bool {
+	return instanceType.NetworkInfo != nil && aws.StringValue(instanceType.NetworkInfo.EfaSupport) == ec2.EfaSupportedFlagSupported
+}
+
+func localNVMeSupported(instanceType *ec2.InstanceTypeInfo) bool {
+	if instanceType.InstanceStorageInfo == nil {
+		return false
+	}
+	nvme := aws.StringValue(instanceType.InstanceStorageInfo.NvmeSupport)
+	return nvme != "" && nvme != ec2.EphemeralNvmeSupportUnsupported
+}
+
+// networkPerformanceGbps parses the human-readable ec2.InstanceTypeInfo.NetworkInfo.NetworkPerformance
+// string (e.g. "25 Gigabit", "Up to 10 Gigabit") into a Gbps integer. ok is false if the field is
+// absent or none of its whitespace-separated tokens parse as an integer, so callers can tell "0 Gbps"
+// apart from "couldn't tell how fast this is" instead of silently treating the latter as the former.
+func networkPerformanceGbps(instanceType *ec2.InstanceTypeInfo) (gbps int64, ok bool) {
+	if instanceType.NetworkInfo == nil || instanceType.NetworkInfo.NetworkPerformance == nil {
+		return 0, false
+	}
+	fields := strings.Fields(*instanceType.NetworkInfo.NetworkPerformance)
+	for _, f := range fields {
+		if v, err := strconv.ParseInt(f, 10, 64); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// networkPerformanceString returns the raw NetworkPerformance field for logging, or "" if unset.
+func networkPerformanceString(instanceType *ec2.InstanceTypeInfo) string {
+	if instanceType.NetworkInfo == nil {
+		return ""
+	}
+	return aws.StringValue(instanceType.NetworkInfo.NetworkPerformance)
+}
+
+// ExtendedResources returns the schedulable extended resources Karpenter should register on the
+// cloudprovider.InstanceType built from instanceType, keyed by resource name, based on the
+// accelerators EC2 reports for it.
+func ExtendedResources(instanceType *ec2.InstanceTypeInfo) map[string]int64 {
+	resources := map[string]int64{}
+	if instanceType.GpuInfo != nil {
+		var nvidiaCount int64
+		for _, gpu := range instanceType.GpuInfo.Gpus {
+			if strings.EqualFold(aws.StringValue(gpu.Manufacturer), "NVIDIA") {
+				nvidiaCount += aws.Int64Value(gpu.Count)
+			}
+		}
+		if nvidiaCount > 0 {
+			resources[ResourceNvidiaGPU] = nvidiaCount
+		}
+	}
+	if count := neuronDeviceCount(instanceType); count > 0 {
+		resources[ResourceAWSNeuron] = count
+	}
+	if efaSupported(instanceType) {
+		resources[ResourceEFA] = 1
+	}
+	return resources
+}
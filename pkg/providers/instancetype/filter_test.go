@@ -0,0 +1,97 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+func TestNetworkPerformanceGbps(t *testing.T) {
+	cases := []struct {
+		name        string
+		performance *string
+		wantGbps    int64
+		wantOK      bool
+	}{
+		{name: "nil NetworkPerformance", performance: nil, wantGbps: 0, wantOK: false},
+		{name: "plain integer", performance: aws.String("25 Gigabit"), wantGbps: 25, wantOK: true},
+		{name: "up to prefix", performance: aws.String("Up to 10 Gigabit"), wantGbps: 10, wantOK: true},
+		{name: "non-numeric rating", performance: aws.String("Low to Moderate"), wantGbps: 0, wantOK: false},
+		{name: "fractional rating", performance: aws.String("12.5 Gigabit"), wantGbps: 0, wantOK: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			it := &ec2.InstanceTypeInfo{NetworkInfo: &ec2.NetworkInfo{NetworkPerformance: c.performance}}
+			gbps, ok := networkPerformanceGbps(it)
+			if gbps != c.wantGbps || ok != c.wantOK {
+				t.Errorf("networkPerformanceGbps(%v) = (%d, %v), want (%d, %v)", aws.StringValue(c.performance), gbps, ok, c.wantGbps, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestMatchesMinimumNetworkPerformance(t *testing.T) {
+	min := int64(20)
+	filter := &v1alpha1.InstanceTypeFilter{MinimumNetworkPerformanceGbps: &min}
+	ctx := context.Background()
+
+	fast := &ec2.InstanceTypeInfo{NetworkInfo: &ec2.NetworkInfo{NetworkPerformance: aws.String("25 Gigabit")}}
+	if !Matches(ctx, fast, filter) {
+		t.Errorf("expected instance type with 25 Gbps to satisfy a 20 Gbps minimum")
+	}
+
+	slow := &ec2.InstanceTypeInfo{NetworkInfo: &ec2.NetworkInfo{NetworkPerformance: aws.String("10 Gigabit")}}
+	if Matches(ctx, slow, filter) {
+		t.Errorf("expected instance type with 10 Gbps to fail a 20 Gbps minimum")
+	}
+
+	// An unparseable rating shouldn't silently behave like "0 Gbps" and wrongly fail the filter.
+	unparseable := &ec2.InstanceTypeInfo{NetworkInfo: &ec2.NetworkInfo{NetworkPerformance: aws.String("Variable")}}
+	if !Matches(ctx, unparseable, filter) {
+		t.Errorf("expected an unparseable network performance rating to be excluded from the check, not fail it")
+	}
+}
+
+func TestMatchesNilFilter(t *testing.T) {
+	it := &ec2.InstanceTypeInfo{}
+	if !Matches(context.Background(), it, nil) {
+		t.Errorf("expected a nil filter to match everything")
+	}
+}
+
+func TestExtendedResources(t *testing.T) {
+	it := &ec2.InstanceTypeInfo{
+		GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{
+			{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(2)},
+		}},
+		NetworkInfo: &ec2.NetworkInfo{EfaSupport: aws.String(ec2.EfaSupportedFlagSupported)},
+	}
+	resources := ExtendedResources(it)
+	if resources[ResourceNvidiaGPU] != 2 {
+		t.Errorf("resources[%s] = %d, want 2", ResourceNvidiaGPU, resources[ResourceNvidiaGPU])
+	}
+	if resources[ResourceEFA] != 1 {
+		t.Errorf("resources[%s] = %d, want 1", ResourceEFA, resources[ResourceEFA])
+	}
+	if _, ok := resources[ResourceAWSNeuron]; ok {
+		t.Errorf("did not expect %s to be registered for a non-Neuron instance type", ResourceAWSNeuron)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+// LeaderAwareSource is a Source that only calls through to the wrapped, EC2-backed source when this
+// replica holds the instance-type discovery Lease. The leader publishes every successful refresh to
+// the InstanceTypeCatalog CRD; non-leader replicas read that CRD instead of calling EC2 themselves,
+// dividing DescribeInstanceTypes/DescribeInstanceTypeOfferings load by the replica count.
+type LeaderAwareSource struct {
+	elector    *LeaderElector
+	leader     Source
+	catalog    *CatalogSource
+	kubeClient client.Client
+
+	mu            sync.Mutex
+	instanceTypes map[string]*ec2.InstanceTypeInfo
+	// zonesBySelector is keyed by subnetSelectorHash, since different AWSNodeTemplates can select
+	// different subnets (and therefore different zones); a single shared map would let whichever
+	// template was resolved most recently clobber every other template's zones.
+	zonesBySelector map[string]map[string]sets.String
+	seqNum          uint64
+}
+
+// NewLeaderAwareSource wraps leaderSource (typically a CachedSource over an EC2Source) so only the
+// Lease holder uses it; other replicas are served from the InstanceTypeCatalog CRD via a
+// CatalogSource backed by kubeClient.
+func NewLeaderAwareSource(elector *LeaderElector, leaderSource Source, kubeClient client.Client) *LeaderAwareSource {
+	return &LeaderAwareSource{
+		elector:    elector,
+		leader:     leaderSource,
+		catalog:    NewCatalogSource(kubeClient),
+		kubeClient: kubeClient,
+	}
+}
+
+func (s *LeaderAwareSource) GetInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	if !s.elector.IsLeader() {
+		return s.catalog.GetInstanceTypes(ctx)
+	}
+	instanceTypes, err := s.leader.GetInstanceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.instanceTypes = instanceTypes
+	ready := readyToPublish(s.instanceTypes, s.zonesBySelector)
+	s.mu.Unlock()
+	if ready {
+		s.publish(ctx)
+	}
+	return instanceTypes, nil
+}
+
+func (s *LeaderAwareSource) GetInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	if !s.elector.IsLeader() {
+		return s.catalog.GetInstanceTypeZones(ctx, nodeTemplate)
+	}
+	zones, err := s.leader.GetInstanceTypeZones(ctx, nodeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	selectorHash, err := subnetSelectorHash(nodeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.zonesBySelector == nil {
+		s.zonesBySelector = map[string]map[string]sets.String{}
+	}
+	s.zonesBySelector[selectorHash] = zones
+	ready := readyToPublish(s.instanceTypes, s.zonesBySelector)
+	s.mu.Unlock()
+	if ready {
+		s.publish(ctx)
+	}
+	return zones, nil
+}
+
+// readyToPublish reports whether the leader's catalog has both instance types and at least one
+// selector's zones populated, so publish is never called before the leader has discovered both. It
+// does not wait for any particular selector: one a follower needs may still be absent when it publishes.
+func readyToPublish(instanceTypes map[string]*ec2.InstanceTypeInfo, zonesBySelector map[string]map[string]sets.String) bool {
+	return instanceTypes != nil && len(zonesBySelector) > 0
+}
+
+// SeqNum returns the change counter a follower should compare against to decide whether its own
+// derived caches (e.g. InstanceTypeProvider.List's result cache) need invalidating. The leader
+// reports its own in-memory counter directly; a follower reads the same counter back from the
+// CatalogSource, since it never calls the wrapped Source itself.
+func (s *LeaderAwareSource) SeqNum(ctx context.Context) (uint64, error) {
+	if !s.elector.IsLeader() {
+		return s.catalog.SeqNum(ctx)
+	}
+	return atomic.LoadUint64(&s.seqNum), nil
+}
+
+// publish writes the leader's current combined view of instanceTypes and every selector's zones to
+// the InstanceTypeCatalog CRD in a single Status update, bumping seqNum by exactly one per publish.
+// It's only called once instanceTypes and at least one selector's zones have been populated, so a
+// follower never observes a write where instanceTypes still reflects pre-startup state.
+func (s *LeaderAwareSource) publish(ctx context.Context) {
+	s.mu.Lock()
+	instanceTypes, zonesBySelector := s.instanceTypes, s.zonesBySelector
+	s.mu.Unlock()
+
+	seqNum := atomic.AddUint64(&s.seqNum, 1)
+	if err := PublishCatalog(ctx, s.kubeClient, instanceTypes, zonesBySelector, seqNum); err != nil {
+		logging.FromContext(ctx).Errorf("publishing instance type catalog, %s", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+func TestReadyToPublish(t *testing.T) {
+	instanceTypes := map[string]*ec2.InstanceTypeInfo{"m5.large": {}}
+	zonesBySelector := map[string]map[string]sets.String{
+		"abc123": {"m5.large": sets.NewString("us-west-2a")},
+	}
+
+	cases := []struct {
+		name            string
+		instanceTypes   map[string]*ec2.InstanceTypeInfo
+		zonesBySelector map[string]map[string]sets.String
+		want            bool
+	}{
+		{name: "neither populated", instanceTypes: nil, zonesBySelector: nil, want: false},
+		{name: "only instance types populated", instanceTypes: instanceTypes, zonesBySelector: nil, want: false},
+		{name: "only zones populated", instanceTypes: nil, zonesBySelector: zonesBySelector, want: false},
+		{name: "both populated", instanceTypes: instanceTypes, zonesBySelector: zonesBySelector, want: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := readyToPublish(c.instanceTypes, c.zonesBySelector); got != c.want {
+				t.Errorf("readyToPublish() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGetInstanceTypeZonesKeepsSelectorsIndependent(t *testing.T) {
+	elector := &LeaderElector{}
+	atomic.StoreInt32(&elector.isLeader, 1)
+	leader := &stubZonesSource{
+		zonesByHash: map[string]map[string]sets.String{},
+	}
+	s := &LeaderAwareSource{elector: elector, leader: leader}
+
+	nodeTemplateA := &v1alpha1.AWSNodeTemplate{Spec: v1alpha1.AWSNodeTemplateSpec{SubnetSelector: map[string]string{"zone": "a"}}}
+	nodeTemplateB := &v1alpha1.AWSNodeTemplate{Spec: v1alpha1.AWSNodeTemplateSpec{SubnetSelector: map[string]string{"zone": "b"}}}
+	leader.nextZones = map[string]sets.String{"m5.large": sets.NewString("us-west-2a")}
+	if _, err := s.GetInstanceTypeZones(context.Background(), nodeTemplateA); err != nil {
+		t.Fatalf("GetInstanceTypeZones(A) error = %v", err)
+	}
+	leader.nextZones = map[string]sets.String{"m5.large": sets.NewString("us-west-2b")}
+	if _, err := s.GetInstanceTypeZones(context.Background(), nodeTemplateB); err != nil {
+		t.Fatalf("GetInstanceTypeZones(B) error = %v", err)
+	}
+
+	if len(s.zonesBySelector) != 2 {
+		t.Fatalf("expected two distinct selectors to be tracked independently, got %d", len(s.zonesBySelector))
+	}
+	for hash, zones := range s.zonesBySelector {
+		_ = hash
+		if zones["m5.large"].Len() != 1 {
+			t.Errorf("expected each selector to keep its own single zone, got %v", zones)
+		}
+	}
+}
+
+// stubZonesSource is a minimal Source test double that returns nextZones from GetInstanceTypeZones
+// regardless of which nodeTemplate is passed, so the test above can drive two different selectors
+// through the same leader without a real EC2Source.
+type stubZonesSource struct {
+	nextZones   map[string]sets.String
+	zonesByHash map[string]map[string]sets.String
+}
+
+func (s *stubZonesSource) GetInstanceTypes(context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	return nil, nil
+}
+
+func (s *stubZonesSource) GetInstanceTypeZones(context.Context, *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	return s.nextZones, nil
+}
+
+func TestLeaderAwareSourceSeqNumAsLeader(t *testing.T) {
+	elector := &LeaderElector{}
+	atomic.StoreInt32(&elector.isLeader, 1)
+	s := &LeaderAwareSource{elector: elector}
+	atomic.StoreUint64(&s.seqNum, 7)
+
+	got, err := s.SeqNum(context.Background())
+	if err != nil {
+		t.Fatalf("SeqNum() error = %v", err)
+	}
+	if got != 7 {
+		t.Errorf("SeqNum() = %d, want 7", got)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"knative.dev/pkg/logging"
+)
+
+// LeaseName is the coordination.k8s.io Lease that selects the single replica responsible for
+// calling EC2 DescribeInstanceTypes/Offerings and publishing the result into InstanceTypeCatalog.
+const LeaseName = "karpenter-instance-type-discovery"
+
+// LeaderElector tracks whether this replica currently holds the discovery Lease. Only the holder
+// should call through to the real (EC2 or static) Source; everyone else should read the published
+// InstanceTypeCatalog via CatalogSource instead.
+type LeaderElector struct {
+	isLeader int32
+}
+
+// NewLeaderElector starts a background leaderelection.LeaderElector against the given Lease in
+// namespace, using identity (typically the pod name) as the holder identity, and returns a
+// LeaderElector whose IsLeader reflects its current state. The election runs until ctx is canceled.
+func NewLeaderElector(ctx context.Context, clientset kubernetes.Interface, namespace, identity string) (*LeaderElector, error) {
+	le := &LeaderElector{}
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		namespace,
+		LeaseName,
+		clientset.CoreV1(),
+		clientset.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return nil, err
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				atomic.StoreInt32(&le.isLeader, 1)
+			},
+			OnStoppedLeading: func() {
+				atomic.StoreInt32(&le.isLeader, 0)
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			elector.Run(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			logging.FromContext(ctx).Debugf("lost instance type discovery leader election, retrying")
+		}
+	}()
+	return le, nil
+}
+
+// IsLeader reports whether this replica currently holds the discovery Lease.
+func (l *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&l.isLeader) == 1
+}
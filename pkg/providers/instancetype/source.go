@@ -0,0 +1,291 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package instancetype provides pluggable backends for discovering the instance types and zonal
+// offerings available to Karpenter, so clusters without EC2 DescribeInstanceTypes permissions can
+// still run Karpenter against a static catalog.
+package instancetype
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+	"github.com/aws/karpenter/pkg/providers/subnet"
+)
+
+// Source discovers the instance types and their zonal offerings available for use by Karpenter.
+// It is the seam between "how do we learn what capacity exists" and the rest of the
+// InstanceTypeProvider, which only cares about the resulting catalog.
+type Source interface {
+	// GetInstanceTypes returns the full catalog of instance types, keyed by instance type name.
+	GetInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error)
+	// GetInstanceTypeZones returns, for each instance type name, the set of zones it can be launched into
+	// given the subnets resolved from the node template's subnet selector.
+	GetInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error)
+}
+
+// SeqNumSource is implemented by Sources that can report a change counter independent of local
+// cache misses. LeaderAwareSource is the motivating case: a follower replica never calls
+// rawInstanceTypes's underlying EC2/static Source itself, so nothing would otherwise bump
+// InstanceTypeProvider.instanceTypesSeqNum for it when the leader's published catalog changes.
+type SeqNumSource interface {
+	Source
+	// SeqNum returns the Source's current change counter.
+	SeqNum(ctx context.Context) (uint64, error)
+}
+
+// EC2Source is the default Source, backed by the EC2 DescribeInstanceTypes and
+// DescribeInstanceTypeOfferings APIs. This is the historical behavior of InstanceTypeProvider.
+type EC2Source struct {
+	ec2api         ec2iface.EC2API
+	subnetProvider *subnet.Provider
+}
+
+func NewEC2Source(ec2api ec2iface.EC2API, subnetProvider *subnet.Provider) *EC2Source {
+	return &EC2Source{ec2api: ec2api, subnetProvider: subnetProvider}
+}
+
+func (s *EC2Source) GetInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	instanceTypes := map[string]*ec2.InstanceTypeInfo{}
+	if err := s.ec2api.DescribeInstanceTypesPagesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("supported-virtualization-type"),
+				Values: []*string{aws.String("hvm")},
+			},
+			{
+				Name:   aws.String("processor-info.supported-architecture"),
+				Values: aws.StringSlice([]string{"x86_64", "arm64"}),
+			},
+		},
+	}, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+		for _, instanceType := range page.InstanceTypes {
+			instanceTypes[aws.StringValue(instanceType.InstanceType)] = instanceType
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("fetching instance types using ec2.DescribeInstanceTypes, %w", err)
+	}
+	return instanceTypes, nil
+}
+
+func (s *EC2Source) GetInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	subnets, err := s.subnetProvider.List(ctx, nodeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("no subnets matched selector %v", nodeTemplate.Spec.SubnetSelector)
+	}
+	zones := sets.NewString(lo.Map(subnets, func(subnet *ec2.Subnet, _ int) string {
+		return aws.StringValue(subnet.AvailabilityZone)
+	})...)
+
+	instanceTypeZones := map[string]sets.String{}
+	if err := s.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{LocationType: aws.String("availability-zone")},
+		func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+			for _, offering := range output.InstanceTypeOfferings {
+				if zones.Has(aws.StringValue(offering.Location)) {
+					if _, ok := instanceTypeZones[aws.StringValue(offering.InstanceType)]; !ok {
+						instanceTypeZones[aws.StringValue(offering.InstanceType)] = sets.NewString()
+					}
+					instanceTypeZones[aws.StringValue(offering.InstanceType)].Insert(aws.StringValue(offering.Location))
+				}
+			}
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("describing instance type zone offerings, %w", err)
+	}
+	return instanceTypeZones, nil
+}
+
+// staticSnapshot is the on-disk/ConfigMap representation loaded by StaticSource. It captures exactly
+// the two things a Source needs to produce: the instance type catalog and the zones each type is
+// offered in, so it can be generated once (e.g. via `aws ec2 describe-instance-types`) and checked in.
+type staticSnapshot struct {
+	InstanceTypes []*ec2.InstanceTypeInfo `json:"instanceTypes"`
+	Zones         map[string][]string     `json:"zones"`
+}
+
+// StaticSource loads a fixed instance-type catalog from a JSON file path (YAML is not parsed), letting clusters
+// without ec2:DescribeInstanceTypes/DescribeInstanceTypeOfferings permissions (e.g. isolated VPCs)
+// run Karpenter against a pre-generated snapshot, and letting tests inject a deterministic catalog
+// without standing up an EC2 client mock.
+type StaticSource struct {
+	path string
+
+	mu            sync.RWMutex
+	instanceTypes map[string]*ec2.InstanceTypeInfo
+	zones         map[string]sets.String
+}
+
+// NewStaticSource constructs a StaticSource that reads its snapshot from the file at path. The file
+// is loaded once and cached for the lifetime of the Source; call Reload to pick up changes.
+func NewStaticSource(path string) (*StaticSource, error) {
+	s := &StaticSource{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStaticSourceFromConfigMap constructs a StaticSource from the snapshot stored under dataKey in
+// a ConfigMap, for clusters that would rather manage the snapshot as a Kubernetes object than a file
+// on the controller's filesystem.
+func NewStaticSourceFromConfigMap(cm *corev1.ConfigMap, dataKey string) (*StaticSource, error) {
+	raw, ok := cm.Data[dataKey]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s/%s has no key %q", cm.Namespace, cm.Name, dataKey)
+	}
+	s := &StaticSource{path: fmt.Sprintf("configmap:%s/%s#%s", cm.Namespace, cm.Name, dataKey)}
+	if err := s.load([]byte(raw)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the snapshot file from disk.
+func (s *StaticSource) Reload() error {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("reading static instance type snapshot %q, %w", s.path, err)
+	}
+	return s.load(raw)
+}
+
+func (s *StaticSource) load(raw []byte) error {
+	var snapshot staticSnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return fmt.Errorf("parsing static instance type snapshot %q, %w", s.path, err)
+	}
+
+	instanceTypes := map[string]*ec2.InstanceTypeInfo{}
+	for _, it := range snapshot.InstanceTypes {
+		instanceTypes[aws.StringValue(it.InstanceType)] = it
+	}
+	zones := map[string]sets.String{}
+	for instanceType, zs := range snapshot.Zones {
+		zones[instanceType] = sets.NewString(zs...)
+	}
+
+	s.mu.Lock()
+	s.instanceTypes = instanceTypes
+	s.zones = zones
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *StaticSource) GetInstanceTypes(_ context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.instanceTypes, nil
+}
+
+func (s *StaticSource) GetInstanceTypeZones(_ context.Context, _ *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.zones, nil
+}
+
+var (
+	cacheHitCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "karpenter",
+		Subsystem: "instance_type_source",
+		Name:      "cache_hit_count",
+		Help:      "The number of cache hits for the instance type source cache, labeled by method.",
+	}, []string{"method"})
+	cacheMissCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "karpenter",
+		Subsystem: "instance_type_source",
+		Name:      "cache_miss_count",
+		Help:      "The number of cache misses for the instance type source cache, labeled by method.",
+	}, []string{"method"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheHitCounter, cacheMissCounter)
+}
+
+const (
+	instanceTypesCacheKey           = "instance-types"
+	instanceTypeZonesCacheKeyPrefix = "instance-type-zones:"
+)
+
+// CachedSource wraps any Source with a TTL-based cache and an explicit Refresh method, so the
+// underlying source (EC2 API calls, file reads) is only consulted once per refresh interval
+// regardless of how many InstanceTypeProvider.List calls come in between.
+type CachedSource struct {
+	source Source
+	ttl    time.Duration
+	cache  *cache.Cache
+}
+
+// NewCachedSource wraps source with a cache that refreshes at most once per refreshInterval.
+func NewCachedSource(source Source, refreshInterval time.Duration) *CachedSource {
+	return &CachedSource{
+		source: source,
+		ttl:    refreshInterval,
+		cache:  cache.New(refreshInterval, refreshInterval),
+	}
+}
+
+func (c *CachedSource) GetInstanceTypes(ctx context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	if cached, ok := c.cache.Get(instanceTypesCacheKey); ok {
+		cacheHitCounter.WithLabelValues("GetInstanceTypes").Inc()
+		return cached.(map[string]*ec2.InstanceTypeInfo), nil
+	}
+	cacheMissCounter.WithLabelValues("GetInstanceTypes").Inc()
+	instanceTypes, err := c.source.GetInstanceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.SetDefault(instanceTypesCacheKey, instanceTypes)
+	return instanceTypes, nil
+}
+
+func (c *CachedSource) GetInstanceTypeZones(ctx context.Context, nodeTemplate *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	key := instanceTypeZonesCacheKeyPrefix + string(nodeTemplate.UID)
+	if cached, ok := c.cache.Get(key); ok {
+		cacheHitCounter.WithLabelValues("GetInstanceTypeZones").Inc()
+		return cached.(map[string]sets.String), nil
+	}
+	cacheMissCounter.WithLabelValues("GetInstanceTypeZones").Inc()
+	zones, err := c.source.GetInstanceTypeZones(ctx, nodeTemplate)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.SetDefault(key, zones)
+	return zones, nil
+}
+
+// Refresh forces the next GetInstanceTypes/GetInstanceTypeZones call to bypass the cache and go
+// back to the underlying Source.
+func (c *CachedSource) Refresh() {
+	c.cache.Flush()
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+// countingSource records how many times its underlying methods are called, so tests can assert
+// CachedSource actually served a cache hit instead of calling through.
+type countingSource struct {
+	instanceTypeCalls int
+	zoneCalls         int
+}
+
+func (c *countingSource) GetInstanceTypes(context.Context) (map[string]*ec2.InstanceTypeInfo, error) {
+	c.instanceTypeCalls++
+	return map[string]*ec2.InstanceTypeInfo{"m5.large": {InstanceType: aws.String("m5.large")}}, nil
+}
+
+func (c *countingSource) GetInstanceTypeZones(context.Context, *v1alpha1.AWSNodeTemplate) (map[string]sets.String, error) {
+	c.zoneCalls++
+	return map[string]sets.String{"m5.large": sets.NewString("us-west-2a")}, nil
+}
+
+func TestCachedSourceHitsAndMisses(t *testing.T) {
+	counting := &countingSource{}
+	cached := NewCachedSource(counting, time.Minute)
+	ctx := context.Background()
+	nodeTemplate := &v1alpha1.AWSNodeTemplate{}
+	nodeTemplate.UID = types.UID("node-template-a")
+
+	if _, err := cached.GetInstanceTypes(ctx); err != nil {
+		t.Fatalf("GetInstanceTypes() error = %v", err)
+	}
+	if _, err := cached.GetInstanceTypes(ctx); err != nil {
+		t.Fatalf("GetInstanceTypes() error = %v", err)
+	}
+	if counting.instanceTypeCalls != 1 {
+		t.Errorf("expected the underlying source to be called once across two GetInstanceTypes calls within the TTL, got %d calls", counting.instanceTypeCalls)
+	}
+
+	if _, err := cached.GetInstanceTypeZones(ctx, nodeTemplate); err != nil {
+		t.Fatalf("GetInstanceTypeZones() error = %v", err)
+	}
+	if _, err := cached.GetInstanceTypeZones(ctx, nodeTemplate); err != nil {
+		t.Fatalf("GetInstanceTypeZones() error = %v", err)
+	}
+	if counting.zoneCalls != 1 {
+		t.Errorf("expected the underlying source to be called once across two GetInstanceTypeZones calls within the TTL, got %d calls", counting.zoneCalls)
+	}
+
+	cached.Refresh()
+	if _, err := cached.GetInstanceTypes(ctx); err != nil {
+		t.Fatalf("GetInstanceTypes() error = %v", err)
+	}
+	if counting.instanceTypeCalls != 2 {
+		t.Errorf("expected Refresh to force the next GetInstanceTypes call through to the source, got %d calls", counting.instanceTypeCalls)
+	}
+}